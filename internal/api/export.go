@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"go_parser/internal/database"
+	"go_parser/internal/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// exportFields - поля записи, которые можно выбрать параметром fields выгрузки
+var exportFields = map[string]bool{
+	"job_id":         true,
+	"url":            true,
+	"domain":         true,
+	"plan":           true,
+	"depth":          true,
+	"data":           true,
+	"links":          true,
+	"parsed_at":      true,
+	"created_at":     true,
+	"updated_at":     true,
+	"error_category": true,
+	"content_hash":   true,
+	"version":        true,
+}
+
+// statsGroups - поля, по которым группируется статистика записей
+var statsGroups = map[string]bool{
+	"plan":           true,
+	"domain":         true,
+	"job_id":         true,
+	"error_category": true,
+}
+
+// handleExportRecords выгружает записи под фильтром в NDJSON, по одной на строку.
+// Выборка читается курсором, поэтому размер выгрузки не ограничен памятью.
+func (s *Server) handleExportRecords(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	filter, msg := recordFilter(q)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	opts := &database.Options{Sort: map[string]int{"_id": 1}}
+	if v := q.Get("fields"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if !exportFields[field] && !strings.HasPrefix(field, "data.") {
+				writeError(w, http.StatusBadRequest, "поле "+field+" нельзя выгрузить")
+				return
+			}
+			opts.Fields = append(opts.Fields, field)
+		}
+	}
+
+	// Ответ уже начат, поэтому ошибку посреди выгрузки можно только записать в лог
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	count := 0
+	for rec, err := range s.records.Stream(r.Context(), filter, opts) {
+		if err != nil {
+			utils.Logger.Printf("Ошибка выгрузки записей после %d шт.: %v", count, err)
+			return
+		}
+		if err := enc.Encode(rec); err != nil {
+			utils.Logger.Printf("Ошибка записи выгрузки после %d шт.: %v", count, err)
+			return
+		}
+		count++
+		if flusher != nil && count%1000 == 0 {
+			flusher.Flush()
+		}
+	}
+}
+
+type recordStats struct {
+	Key          interface{} `json:"key"`
+	Count        int64       `json:"count"`
+	LastParsedAt *time.Time  `json:"last_parsed_at,omitempty"`
+}
+
+// handleRecordStats считает записи под фильтром по значениям поля by.
+func (s *Server) handleRecordStats(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	by := q.Get("by")
+	if by == "" {
+		by = "plan"
+	}
+	if !statsGroups[by] {
+		writeError(w, http.StatusBadRequest, "группировка по полю "+by+" не поддерживается")
+		return
+	}
+
+	filter, msg := recordFilter(q)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	pipeline := []map[string]interface{}{
+		{"$match": filter},
+		{"$group": map[string]interface{}{
+			"_id":            "$" + by,
+			"count":          map[string]interface{}{"$sum": 1},
+			"last_parsed_at": map[string]interface{}{"$max": "$parsed_at"},
+		}},
+		{"$sort": map[string]interface{}{"count": -1}},
+	}
+
+	items := []recordStats{}
+	for doc, err := range s.records.Aggregate(r.Context(), pipeline) {
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "ошибка подсчёта статистики")
+			return
+		}
+		items = append(items, toRecordStats(doc))
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"by": by, "items": items})
+}
+
+func toRecordStats(doc map[string]interface{}) recordStats {
+	st := recordStats{Key: doc["_id"]}
+	switch n := doc["count"].(type) {
+	case int32:
+		st.Count = int64(n)
+	case int64:
+		st.Count = n
+	}
+	// Даты приходят из драйвера как primitive.DateTime
+	if t, ok := doc["last_parsed_at"].(interface{ Time() time.Time }); ok {
+		v := t.Time().UTC()
+		st.LastParsedAt = &v
+	}
+	return st
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"errors"
+	"go_parser/internal/database"
+	"go_parser/internal/domain/job"
+	"go_parser/internal/jobs"
+	"go_parser/internal/utils"
+	"net/http"
+)
+
+func validateBudget(b job.Budget) string {
+	if b.MaxPages < 0 || b.MaxDurationSec < 0 || b.MaxDepth < 0 {
+		return "ограничения budget не могут быть отрицательными"
+	}
+	return ""
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	filter := database.Filter{}
+	for _, field := range []string{"status", "seed_url", "plan"} {
+		if v := q.Get(field); v != "" {
+			filter[field] = v
+		}
+	}
+
+	sortParam := q.Get("sort")
+	if sortParam == "" {
+		sortParam = "-created_at"
+	}
+	opts, msg := parsePaging(q.Get("page"), q.Get("limit"), sortParam)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	ctx := r.Context()
+	items, err := s.jobRepo.Find(ctx, filter, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка чтения заданий")
+		return
+	}
+	total, err := s.jobRepo.Count(ctx, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка подсчёта заданий")
+		return
+	}
+
+	if items == nil {
+		items = []*job.Job{}
+	}
+
+	writeJSON(w, http.StatusOK, listResponse[*job.Job]{
+		Items: items,
+		Total: total,
+		Page:  opts.Offset/opts.Limit + 1,
+		Limit: opts.Limit,
+	})
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	j, err := s.jobRepo.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка чтения задания")
+		return
+	}
+	if j == nil {
+		writeError(w, http.StatusNotFound, "задание не найдено")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, j)
+}
+
+func (s *Server) handlePauseJob(w http.ResponseWriter, r *http.Request) {
+	s.controlJob(w, r, func(id string) error {
+		return s.jobs.Pause(r.Context(), id)
+	})
+}
+
+// handleResumeJob снимает паузу и заново публикует задачи, отложенные на время паузы.
+func (s *Server) handleResumeJob(w http.ResponseWriter, r *http.Request) {
+	s.controlJob(w, r, func(id string) error {
+		parked, err := s.jobs.Resume(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range parked {
+			if err := s.publisher.Resubmit(r.Context(), t); err != nil {
+				// Задача остаётся отложенной, повторный resume опубликует её снова
+				utils.Logger.Printf("Ошибка повторной публикации задачи %s: %v", t.GetID(), err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	s.controlJob(w, r, func(id string) error {
+		return s.jobs.Cancel(r.Context(), id)
+	})
+}
+
+// controlJob выполняет операцию над заданием и отвечает его актуальным состоянием.
+func (s *Server) controlJob(w http.ResponseWriter, r *http.Request, op func(id string) error) {
+	id := r.PathValue("id")
+	ctx := r.Context()
+
+	j, err := s.jobRepo.Get(ctx, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка чтения задания")
+		return
+	}
+	if j == nil {
+		writeError(w, http.StatusNotFound, "задание не найдено")
+		return
+	}
+
+	if err := op(id); err != nil {
+		if errors.Is(err, jobs.ErrInvalidState) {
+			writeError(w, http.StatusConflict, "операция недоступна в статусе "+j.Status)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "ошибка изменения задания")
+		return
+	}
+
+	if j, err = s.jobRepo.Get(ctx, id); err != nil || j == nil {
+		writeError(w, http.StatusInternalServerError, "ошибка чтения задания")
+		return
+	}
+	writeJSON(w, http.StatusOK, j)
+}
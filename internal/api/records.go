@@ -0,0 +1,334 @@
+package api
+
+import (
+	"go_parser/internal/changes"
+	"go_parser/internal/database"
+	"go_parser/internal/domain/record"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+var sortableFields = map[string]bool{
+	"parsed_at":   true,
+	"created_at":  true,
+	"updated_at":  true,
+	"url":         true,
+	"depth":       true,
+	"next_run":    true,
+	"version":     true,
+	"detected_at": true,
+}
+
+// listResponse - страница списка. Next - токен следующей страницы для параметра after
+// (keyset-пагинация), пуст на последней странице.
+type listResponse[T any] struct {
+	Items []T    `json:"items"`
+	Total int64  `json:"total"`
+	Page  int64  `json:"page,omitempty"`
+	Limit int64  `json:"limit"`
+	Next  string `json:"next,omitempty"`
+}
+
+func (s *Server) handleListRecords(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	filter, msg := recordFilter(q)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	opts, msg := parsePaging(q.Get("page"), q.Get("limit"), q.Get("sort"))
+	if msg == "" {
+		msg = parseAfter(opts, q.Get("after"))
+	}
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	ctx := r.Context()
+	items, err := s.records.Find(ctx, filter, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка чтения записей")
+		return
+	}
+	total, err := s.records.Count(ctx, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка подсчёта записей")
+		return
+	}
+
+	if items == nil {
+		items = []*record.Record{}
+	}
+
+	next, err := nextPage(items, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка построения следующей страницы")
+		return
+	}
+
+	resp := listResponse[*record.Record]{
+		Items: items,
+		Total: total,
+		Limit: opts.Limit,
+		Next:  next,
+	}
+	if opts.After == nil {
+		resp.Page = opts.Offset/opts.Limit + 1
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// recordFilter - фильтр записей из параметров запроса, общий для списка, выгрузки и статистики.
+// Значения идут в запрос только как значения условий, операторы задаёт сервер.
+func recordFilter(q url.Values) (database.Filter, string) {
+	var conds []database.Cond
+	for _, field := range []string{"url", "domain", "job_id", "error_category"} {
+		if v := q.Get(field); v != "" {
+			conds = append(conds, database.Eq(field, v))
+		}
+	}
+	// plan=a,b - записи любого из планов
+	if v := q.Get("plan"); v != "" {
+		conds = append(conds, database.In("plan", strings.Split(v, ",")...))
+	}
+	if v := q.Get("url_prefix"); v != "" {
+		conds = append(conds, database.Regex("url", "^"+regexp.QuoteMeta(v)))
+	}
+	if v := q.Get("depth"); v != "" {
+		depth, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, "параметр depth должен быть числом"
+		}
+		conds = append(conds, database.Eq("depth", depth))
+	}
+	if v := q.Get("failed"); v != "" {
+		failed, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, "параметр failed должен быть true или false"
+		}
+		conds = append(conds, database.Exists("error_category", failed))
+	}
+
+	from, msg := parseTimeParam(q, "parsed_from")
+	if msg != "" {
+		return nil, msg
+	}
+	to, msg := parseTimeParam(q, "parsed_to")
+	if msg != "" {
+		return nil, msg
+	}
+	if from != nil || to != nil {
+		conds = append(conds, database.Range("parsed_at", from, to))
+	}
+
+	return database.Where(conds...), ""
+}
+
+// parseTimeParam разбирает время в RFC 3339; nil - параметр не задан.
+func parseTimeParam(q url.Values, name string) (interface{}, string) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, ""
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, "параметр " + name + " должен быть временем в формате RFC 3339"
+	}
+	return t, ""
+}
+
+func (s *Server) handleGetRecord(w http.ResponseWriter, r *http.Request) {
+	rec, err := s.records.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка чтения записи")
+		return
+	}
+	if rec == nil {
+		writeError(w, http.StatusNotFound, "запись не найдена")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rec)
+}
+
+// handleRecordHistory отдаёт прежние версии записи, новые первыми.
+func (s *Server) handleRecordHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	sortParam := q.Get("sort")
+	if sortParam == "" {
+		sortParam = "-version"
+	}
+	opts, msg := parsePaging(q.Get("page"), q.Get("limit"), sortParam)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	ctx := r.Context()
+	id := r.PathValue("id")
+
+	rec, err := s.records.Get(ctx, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка чтения записи")
+		return
+	}
+	if rec == nil {
+		writeError(w, http.StatusNotFound, "запись не найдена")
+		return
+	}
+
+	filter := database.Filter{"record_id": id}
+	items, err := s.history.Find(ctx, filter, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка чтения истории записи")
+		return
+	}
+	total, err := s.history.Count(ctx, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка подсчёта версий записи")
+		return
+	}
+
+	if items == nil {
+		items = []*record.History{}
+	}
+
+	writeJSON(w, http.StatusOK, listResponse[*record.History]{
+		Items: items,
+		Total: total,
+		Page:  opts.Offset/opts.Limit + 1,
+		Limit: opts.Limit,
+	})
+}
+
+// handleListChanges отдаёт события изменений записей, новые первыми.
+func (s *Server) handleListChanges(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	filter := database.Filter{}
+	for _, field := range []string{"record_id", "url", "plan", "job_id"} {
+		if v := q.Get(field); v != "" {
+			filter[field] = v
+		}
+	}
+
+	sortParam := q.Get("sort")
+	if sortParam == "" {
+		sortParam = "-detected_at"
+	}
+	opts, msg := parsePaging(q.Get("page"), q.Get("limit"), sortParam)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	ctx := r.Context()
+	items, err := s.changes.Find(ctx, filter, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка чтения изменений")
+		return
+	}
+	total, err := s.changes.Count(ctx, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка подсчёта изменений")
+		return
+	}
+
+	if items == nil {
+		items = []*changes.Event{}
+	}
+
+	writeJSON(w, http.StatusOK, listResponse[*changes.Event]{
+		Items: items,
+		Total: total,
+		Page:  opts.Offset/opts.Limit + 1,
+		Limit: opts.Limit,
+	})
+}
+
+// parseAfter переключает выборку на keyset-пагинацию: вместо page берётся позиция
+// из токена next предыдущей страницы. Глубокие страницы так не требуют пропуска документов.
+func parseAfter(opts *database.Options, token string) string {
+	if token == "" {
+		return ""
+	}
+	after, err := database.DecodeKeyset(token)
+	if err != nil {
+		return "параметр after: " + err.Error()
+	}
+	if _, ok := opts.Sort[after.Field]; !ok {
+		return "параметр after получен для другой сортировки"
+	}
+	opts.Offset = 0
+	opts.After = after
+	return ""
+}
+
+// nextPage - токен позиции после последнего элемента полной страницы.
+func nextPage[T database.Entity](items []T, opts *database.Options) (string, error) {
+	if int64(len(items)) < opts.Limit {
+		return "", nil
+	}
+
+	var field string
+	for f := range opts.Sort {
+		field = f
+	}
+	after, err := database.KeysetOf(items[len(items)-1], field)
+	if err != nil {
+		return "", err
+	}
+	return after.Encode()
+}
+
+// parsePaging разбирает page/limit/sort. sort задаётся как "field" или "-field".
+func parsePaging(pageParam, limitParam, sortParam string) (*database.Options, string) {
+	page, limit := int64(1), int64(defaultLimit)
+
+	if pageParam != "" {
+		v, err := strconv.ParseInt(pageParam, 10, 64)
+		if err != nil || v < 1 {
+			return nil, "параметр page должен быть положительным числом"
+		}
+		page = v
+	}
+	if limitParam != "" {
+		v, err := strconv.ParseInt(limitParam, 10, 64)
+		if err != nil || v < 1 {
+			return nil, "параметр limit должен быть положительным числом"
+		}
+		limit = min(v, maxLimit)
+	}
+
+	if sortParam == "" {
+		sortParam = "-parsed_at"
+	}
+	order := 1
+	field := sortParam
+	if strings.HasPrefix(sortParam, "-") {
+		order = -1
+		field = sortParam[1:]
+	}
+	if !sortableFields[field] {
+		return nil, "сортировка по полю " + field + " не поддерживается"
+	}
+
+	return &database.Options{
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+		Sort:   map[string]int{field: order},
+	}, ""
+}
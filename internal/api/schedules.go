@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"go_parser/internal/database"
+	"go_parser/internal/domain/job"
+	"go_parser/internal/domain/schedule"
+	"go_parser/internal/domain/task"
+	"go_parser/internal/scheduler"
+	"net/http"
+	"time"
+)
+
+type scheduleRequest struct {
+	Name     string                 `json:"name"`
+	Cron     string                 `json:"cron"`
+	URL      string                 `json:"url"`
+	Plan     string                 `json:"plan"`
+	MaxDepth int                    `json:"max_depth"`
+	Options  map[string]interface{} `json:"options"`
+	Budget   job.Budget             `json:"budget"`
+	// Enabled по умолчанию true
+	Enabled *bool `json:"enabled"`
+}
+
+func (s *Server) handleCreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req scheduleRequest
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "невалидный JSON: "+err.Error())
+		return
+	}
+
+	seed := task.Task{URL: req.URL, Plan: req.Plan, MaxDepth: req.MaxDepth, Options: req.Options}
+	if msg := s.validateTask(&seed); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := validateBudget(req.Budget); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	now := time.Now().UTC()
+	next, err := scheduler.Next(req.Cron, now)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "невалидное cron-выражение: "+err.Error())
+		return
+	}
+
+	sc := &schedule.Schedule{
+		Name:      req.Name,
+		Cron:      req.Cron,
+		URL:       req.URL,
+		Plan:      req.Plan,
+		MaxDepth:  req.MaxDepth,
+		Options:   req.Options,
+		Budget:    req.Budget,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+		NextRun:   next,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.schedules.Create(r.Context(), sc); err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка сохранения расписания")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, sc)
+}
+
+func (s *Server) handleListSchedules(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	filter := database.Filter{}
+	for _, field := range []string{"name", "url", "plan"} {
+		if v := q.Get(field); v != "" {
+			filter[field] = v
+		}
+	}
+
+	sortParam := q.Get("sort")
+	if sortParam == "" {
+		sortParam = "-created_at"
+	}
+	opts, msg := parsePaging(q.Get("page"), q.Get("limit"), sortParam)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	ctx := r.Context()
+	items, err := s.schedules.Find(ctx, filter, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка чтения расписаний")
+		return
+	}
+	total, err := s.schedules.Count(ctx, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка подсчёта расписаний")
+		return
+	}
+
+	if items == nil {
+		items = []*schedule.Schedule{}
+	}
+
+	writeJSON(w, http.StatusOK, listResponse[*schedule.Schedule]{
+		Items: items,
+		Total: total,
+		Page:  opts.Offset/opts.Limit + 1,
+		Limit: opts.Limit,
+	})
+}
+
+func (s *Server) handleGetSchedule(w http.ResponseWriter, r *http.Request) {
+	sc, err := s.schedules.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка чтения расписания")
+		return
+	}
+	if sc == nil {
+		writeError(w, http.StatusNotFound, "расписание не найдено")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sc)
+}
+
+func (s *Server) handleDeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.PathValue("id")
+
+	sc, err := s.schedules.Get(ctx, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка чтения расписания")
+		return
+	}
+	if sc == nil {
+		writeError(w, http.StatusNotFound, "расписание не найдено")
+		return
+	}
+
+	if err := s.schedules.Delete(ctx, id); err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка удаления расписания")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
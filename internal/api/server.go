@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go_parser/internal/changes"
+	"go_parser/internal/database"
+	"go_parser/internal/domain/job"
+	"go_parser/internal/domain/plan"
+	"go_parser/internal/domain/record"
+	"go_parser/internal/domain/schedule"
+	"go_parser/internal/domain/task"
+	"go_parser/internal/queue"
+	"go_parser/internal/utils"
+	"net/http"
+	"time"
+)
+
+type PlanRegister interface {
+	Get(name string) (plan.Plan, error)
+	Resolve(url string) (plan.Plan, error)
+}
+
+type TaskPublisher interface {
+	Submit(ctx context.Context, t *task.Task) error
+	Resubmit(ctx context.Context, t *task.Task) error
+}
+
+// JobController создаёт задания обхода и управляет ими
+type JobController interface {
+	Start(ctx context.Context, seed *task.Task, budget job.Budget) (*job.Job, error)
+	Fail(ctx context.Context, id string, reason error) error
+	Pause(ctx context.Context, id string) error
+	Resume(ctx context.Context, id string) ([]*task.Task, error)
+	Cancel(ctx context.Context, id string) error
+}
+
+// QueueState - состояние соединения с очередью для health-check
+type QueueState interface {
+	State() string
+}
+
+type Server struct {
+	srv       *http.Server
+	records   database.Repository[*record.Record]
+	history   database.Repository[*record.History]
+	changes   database.Repository[*changes.Event]
+	tasks     database.Repository[*task.Task]
+	jobRepo   database.Repository[*job.Job]
+	jobs      JobController
+	schedules database.Repository[*schedule.Schedule]
+	plans     PlanRegister
+	publisher TaskPublisher
+	queue     QueueState
+}
+
+func NewServer(
+	addr string,
+	records database.Repository[*record.Record],
+	history database.Repository[*record.History],
+	changes database.Repository[*changes.Event],
+	tasks database.Repository[*task.Task],
+	jobRepo database.Repository[*job.Job],
+	jobs JobController,
+	schedules database.Repository[*schedule.Schedule],
+	plans PlanRegister,
+	publisher TaskPublisher,
+	queue QueueState,
+) *Server {
+	s := &Server{
+		records:   records,
+		history:   history,
+		changes:   changes,
+		tasks:     tasks,
+		jobRepo:   jobRepo,
+		jobs:      jobs,
+		schedules: schedules,
+		plans:     plans,
+		publisher: publisher,
+		queue:     queue,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /parse", s.handleParse)
+	mux.HandleFunc("GET /records", s.handleListRecords)
+	mux.HandleFunc("GET /records/export", s.handleExportRecords)
+	mux.HandleFunc("GET /records/stats", s.handleRecordStats)
+	mux.HandleFunc("GET /records/{id}", s.handleGetRecord)
+	mux.HandleFunc("GET /records/{id}/history", s.handleRecordHistory)
+	mux.HandleFunc("GET /changes", s.handleListChanges)
+	mux.HandleFunc("GET /tasks", s.handleListTasks)
+	mux.HandleFunc("GET /tasks/{id}", s.handleGetTask)
+	mux.HandleFunc("GET /jobs", s.handleListJobs)
+	mux.HandleFunc("GET /jobs/{id}", s.handleGetJob)
+	mux.HandleFunc("POST /jobs/{id}/pause", s.handlePauseJob)
+	mux.HandleFunc("POST /jobs/{id}/resume", s.handleResumeJob)
+	mux.HandleFunc("POST /jobs/{id}/cancel", s.handleCancelJob)
+	mux.HandleFunc("POST /schedules", s.handleCreateSchedule)
+	mux.HandleFunc("GET /schedules", s.handleListSchedules)
+	mux.HandleFunc("GET /schedules/{id}", s.handleGetSchedule)
+	mux.HandleFunc("DELETE /schedules/{id}", s.handleDeleteSchedule)
+	mux.HandleFunc("GET /health", s.handleHealth)
+
+	s.srv = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return s
+}
+
+func (s *Server) Start() {
+	go func() {
+		utils.Logger.Printf("HTTP сервер слушает %s", s.srv.Addr)
+		if err := s.srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			utils.Logger.Printf("Ошибка HTTP сервера: %v", err)
+		}
+	}()
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// handleHealth отдаёт 503, пока соединение с очередью не установлено.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	state := s.queue.State()
+
+	status := http.StatusOK
+	if state != queue.StateConnected {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, status, map[string]string{"queue": state})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		utils.Logger.Printf("Ошибка записи ответа: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
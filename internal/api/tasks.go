@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"go_parser/internal/database"
+	"go_parser/internal/domain/job"
+	"go_parser/internal/domain/plan"
+	"go_parser/internal/domain/task"
+	"go_parser/internal/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const maxRequestBody = 1 << 20
+
+// parseRequest - seed-задача и бюджет задания обхода
+type parseRequest struct {
+	task.Task
+	Budget job.Budget `json:"budget"`
+}
+
+type parseResponse struct {
+	TaskID string `json:"task_id"`
+	JobID  string `json:"job_id"`
+	Status string `json:"status"`
+}
+
+func (s *Server) handleParse(w http.ResponseWriter, r *http.Request) {
+	var req parseRequest
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "невалидный JSON: "+err.Error())
+		return
+	}
+
+	t := req.Task
+	if msg := s.validateTask(&t); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := validateBudget(req.Budget); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	// Клиент задаёт только seed-задачу, служебные поля заполняем сами
+	t.SetID("")
+	t.Depth = 0
+	t.ParentURL = ""
+	t.RetryCount = 0
+	t.Error = ""
+	t.JobID = ""
+
+	if _, err := s.jobs.Start(r.Context(), &t, req.Budget); err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка создания задания")
+		return
+	}
+
+	if err := s.publisher.Submit(r.Context(), &t); err != nil {
+		if err := s.jobs.Fail(r.Context(), t.JobID, err); err != nil {
+			utils.Logger.Printf("Ошибка обновления задания %s: %v", t.JobID, err)
+		}
+		writeError(w, http.StatusInternalServerError, "ошибка постановки задачи в очередь")
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, parseResponse{
+		TaskID: t.GetID(),
+		JobID:  t.JobID,
+		Status: t.Status,
+	})
+}
+
+func (s *Server) validateTask(t *task.Task) string {
+	if t.URL == "" {
+		return "поле url обязательно"
+	}
+
+	u, err := url.Parse(t.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "поле url должно быть абсолютным http(s) адресом"
+	}
+
+	if plan.IsAuto(t.Plan) {
+		if _, err := s.plans.Resolve(t.URL); err != nil {
+			return err.Error()
+		}
+	} else if _, err := s.plans.Get(t.Plan); err != nil {
+		return err.Error()
+	}
+
+	if t.MaxDepth < 0 {
+		return "поле max_depth не может быть отрицательным"
+	}
+
+	if _, err := plan.TimeoutOption(t.Options); err != nil {
+		return "options: " + err.Error()
+	}
+
+	return ""
+}
+
+func (s *Server) handleListTasks(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	filter := database.Filter{}
+	for _, field := range []string{"url", "plan", "status", "parent_url", "job_id"} {
+		if v := q.Get(field); v != "" {
+			filter[field] = v
+		}
+	}
+	if v := q.Get("depth"); v != "" {
+		depth, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "параметр depth должен быть числом")
+			return
+		}
+		filter["depth"] = depth
+	}
+
+	sortParam := q.Get("sort")
+	if sortParam == "" {
+		sortParam = "-updated_at"
+	}
+	opts, msg := parsePaging(q.Get("page"), q.Get("limit"), sortParam)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	ctx := r.Context()
+	items, err := s.tasks.Find(ctx, filter, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка чтения задач")
+		return
+	}
+	total, err := s.tasks.Count(ctx, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка подсчёта задач")
+		return
+	}
+
+	if items == nil {
+		items = []*task.Task{}
+	}
+
+	writeJSON(w, http.StatusOK, listResponse[*task.Task]{
+		Items: items,
+		Total: total,
+		Page:  opts.Offset/opts.Limit + 1,
+		Limit: opts.Limit,
+	})
+}
+
+func (s *Server) handleGetTask(w http.ResponseWriter, r *http.Request) {
+	t, err := s.tasks.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ошибка чтения задачи")
+		return
+	}
+	if t == nil {
+		writeError(w, http.StatusNotFound, "задача не найдена")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, t)
+}
@@ -0,0 +1,93 @@
+package changes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go_parser/internal/database"
+	"go_parser/internal/domain/queue"
+	"go_parser/internal/domain/record"
+	"strconv"
+	"time"
+)
+
+// Event - изменения записи между соседними версиями.
+type Event struct {
+	database.BaseEntity `bson:",inline"`
+
+	RecordID    string    `bson:"record_id" json:"record_id"`
+	JobID       string    `bson:"job_id,omitempty" json:"job_id,omitempty"`
+	URL         string    `bson:"url" json:"url"`
+	PlanName    string    `bson:"plan" json:"plan"`
+	Version     int       `bson:"version" json:"version"`
+	PrevVersion int       `bson:"prev_version" json:"prev_version"`
+	Changes     []Change  `bson:"changes" json:"changes"`
+	DetectedAt  time.Time `bson:"detected_at" json:"detected_at"`
+}
+
+// EventIndexes - индексы коллекции record_changes.
+var EventIndexes = []database.Index{
+	{Keys: []database.IndexKey{database.Asc("record_id"), database.Desc("version")}},
+	{Keys: []database.IndexKey{database.Desc("detected_at"), database.Desc("_id")}},
+	{Keys: []database.IndexKey{database.Asc("plan"), database.Desc("detected_at"), database.Desc("_id")}},
+	{Keys: []database.IndexKey{database.Asc("job_id"), database.Desc("detected_at"), database.Desc("_id")}},
+}
+
+// Detector сравнивает версии записи, сохраняет событие и рассылает его
+// в exchange с routing key - именем плана.
+type Detector struct {
+	repo      database.Repository[*Event]
+	publisher queue.Publisher
+	exchange  string
+	listKeys  []string
+}
+
+// exchange == "" - события только сохраняются
+func NewDetector(repo database.Repository[*Event], publisher queue.Publisher, exchange string, listKeys []string) *Detector {
+	return &Detector{
+		repo:      repo,
+		publisher: publisher,
+		exchange:  exchange,
+		listKeys:  listKeys,
+	}
+}
+
+func (d *Detector) Detect(ctx context.Context, prev *record.History, cur *record.Record) error {
+	changes, err := Diff(prev.Data, cur.Data, d.listKeys)
+	if err != nil {
+		return fmt.Errorf("ошибка сравнения версий %s: %w", cur.GetID(), err)
+	}
+	// Хэш учитывает представление данных, а diff - только значения
+	if len(changes) == 0 {
+		return nil
+	}
+
+	ev := &Event{
+		RecordID:    cur.GetID(),
+		JobID:       cur.JobID,
+		URL:         cur.URL,
+		PlanName:    cur.PlanName,
+		Version:     cur.Version,
+		PrevVersion: prev.Version,
+		Changes:     changes,
+		DetectedAt:  time.Now(),
+	}
+	ev.SetID(cur.GetID() + ":" + strconv.Itoa(cur.Version))
+
+	if err := d.repo.Create(ctx, ev); err != nil && !database.IsDuplicate(err) {
+		return fmt.Errorf("ошибка сохранения изменений %s: %w", ev.GetID(), err)
+	}
+
+	if d.exchange == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return d.publisher.Publish(ctx, cur.PlanName, queue.Publishing{
+		Body:     body,
+		Exchange: d.exchange,
+	})
+}
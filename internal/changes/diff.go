@@ -0,0 +1,200 @@
+package changes
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Виды изменения по пути
+const (
+	OpAdded   = "added"
+	OpRemoved = "removed"
+	OpChanged = "changed"
+)
+
+// Change - изменение одного значения. Path строится как в JSON: "items[post_id=123].points",
+// для списков без ключа - "tags[2]".
+type Change struct {
+	Path string      `json:"path" bson:"path"`
+	Op   string      `json:"op" bson:"op"`
+	Old  interface{} `json:"old,omitempty" bson:"old"`
+	New  interface{} `json:"new,omitempty" bson:"new"`
+}
+
+// Diff сравнивает данные двух версий записи. Элементы списков объектов сопоставляются
+// по первому из listKeys, который есть у всех элементов обеих версий, иначе по индексу.
+func Diff(prev, cur map[string]interface{}, listKeys []string) ([]Change, error) {
+	a, err := normalize(prev)
+	if err != nil {
+		return nil, err
+	}
+	b, err := normalize(cur)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &differ{listKeys: listKeys}
+	d.compare("", a, b)
+
+	sort.SliceStable(d.changes, func(i, j int) bool {
+		return d.changes[i].Path < d.changes[j].Path
+	})
+	return d.changes, nil
+}
+
+type differ struct {
+	listKeys []string
+	changes  []Change
+}
+
+func (d *differ) compare(path string, a, b interface{}) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			d.compareMaps(path, av, bv)
+			return
+		}
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok {
+			d.compareLists(path, av, bv)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		d.changes = append(d.changes, Change{Path: path, Op: OpChanged, Old: a, New: b})
+	}
+}
+
+func (d *differ) compareMaps(path string, a, b map[string]interface{}) {
+	for k, av := range a {
+		bv, ok := b[k]
+		if !ok {
+			d.changes = append(d.changes, Change{Path: join(path, k), Op: OpRemoved, Old: av})
+			continue
+		}
+		d.compare(join(path, k), av, bv)
+	}
+	for k, bv := range b {
+		if _, ok := a[k]; !ok {
+			d.changes = append(d.changes, Change{Path: join(path, k), Op: OpAdded, New: bv})
+		}
+	}
+}
+
+func (d *differ) compareLists(path string, a, b []interface{}) {
+	key := d.matchKey(a, b)
+	if key == "" {
+		for i := 0; i < max(len(a), len(b)); i++ {
+			p := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(b):
+				d.changes = append(d.changes, Change{Path: p, Op: OpRemoved, Old: a[i]})
+			case i >= len(a):
+				d.changes = append(d.changes, Change{Path: p, Op: OpAdded, New: b[i]})
+			default:
+				d.compare(p, a[i], b[i])
+			}
+		}
+		return
+	}
+
+	index := func(items []interface{}) map[string]interface{} {
+		m := make(map[string]interface{}, len(items))
+		for _, item := range items {
+			m[keyValue(item, key)] = item
+		}
+		return m
+	}
+	am, bm := index(a), index(b)
+
+	for k, av := range am {
+		p := fmt.Sprintf("%s[%s=%s]", path, key, k)
+		bv, ok := bm[k]
+		if !ok {
+			d.changes = append(d.changes, Change{Path: p, Op: OpRemoved, Old: av})
+			continue
+		}
+		d.compare(p, av, bv)
+	}
+	for k, bv := range bm {
+		if _, ok := am[k]; !ok {
+			d.changes = append(d.changes, Change{Path: fmt.Sprintf("%s[%s=%s]", path, key, k), Op: OpAdded, New: bv})
+		}
+	}
+}
+
+// matchKey - ключ, уникальный и заполненный у всех элементов обоих списков; "" - сравнение по индексу.
+func (d *differ) matchKey(a, b []interface{}) string {
+	if len(a) == 0 && len(b) == 0 {
+		return ""
+	}
+
+next:
+	for _, key := range d.listKeys {
+		for _, items := range [][]interface{}{a, b} {
+			seen := make(map[string]bool, len(items))
+			for _, item := range items {
+				obj, ok := item.(map[string]interface{})
+				if !ok || obj[key] == nil {
+					continue next
+				}
+				v := keyValue(obj, key)
+				if seen[v] {
+					continue next
+				}
+				seen[v] = true
+			}
+		}
+		return key
+	}
+	return ""
+}
+
+func keyValue(item interface{}, key string) string {
+	return fmt.Sprint(item.(map[string]interface{})[key])
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// normalize приводит данные к типам JSON. Версия из MongoDB и свежий результат плана
+// иначе различаются типами (int32 и int, primitive.A и срез структур) при равных значениях.
+func normalize(data map[string]interface{}) (interface{}, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	return normalizeTimes(v), nil
+}
+
+// normalizeTimes приводит время к UTC с точностью до миллисекунд: так его хранит MongoDB.
+func normalizeTimes(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = normalizeTimes(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = normalizeTimes(item)
+		}
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, val); err == nil {
+			return t.UTC().Truncate(time.Millisecond).Format(time.RFC3339Nano)
+		}
+	}
+	return v
+}
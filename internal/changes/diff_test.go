@@ -0,0 +1,286 @@
+package changes
+
+import (
+	"go_parser/internal/parser/plans"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type obj = map[string]interface{}
+type list = []interface{}
+
+// stored возвращает данные такими, какими их прочитает Detector из MongoDB
+func stored(data obj) obj {
+	raw, err := bson.Marshal(data)
+	if err != nil {
+		panic(err)
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+// hnCrawl - данные одной страницы поста HN в том виде, в каком их возвращает план
+func hnCrawl() obj {
+	posted := time.Unix(1740821400, 0).UTC()
+	return obj{
+		"post": plans.PostData{
+			Title: "First post", URL: "https://example.com/a", Points: 120,
+			Author: "alice", PostedTime: posted, Comments: 2, PostID: "101",
+		},
+		"comments": []plans.CommentData{
+			{Author: "carol", Text: "top level", Time: posted.Add(2 * time.Hour), ParentID: "101"},
+			{Author: "dave", Text: "reply", ParentID: "201", Level: 1},
+		},
+		"comments_count": 2,
+	}
+}
+
+func TestDiff(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	posted := time.Date(2025, 3, 1, 12, 30, 0, 123456789, moscow)
+
+	tests := []struct {
+		name     string
+		prev     obj
+		cur      obj
+		listKeys []string
+		want     []Change
+	}{
+		{
+			name: "без изменений",
+			prev: obj{"title": "a", "points": 1},
+			cur:  obj{"title": "a", "points": 1},
+		},
+		{
+			name: "int32 из базы и int из плана",
+			prev: obj{"points": int32(10), "ratio": 0.5, "nested": obj{"n": int64(3)}},
+			cur:  obj{"points": 10, "ratio": float32(0.5), "nested": obj{"n": 3.0}},
+		},
+		{
+			name: "время из базы и из плана",
+			prev: obj{"posted": primitive.NewDateTimeFromTime(posted)},
+			cur:  obj{"posted": posted},
+		},
+		{
+			name: "primitive.A и срез",
+			prev: obj{"tags": primitive.A{"go", "db"}},
+			cur:  obj{"tags": []string{"go", "db"}},
+		},
+		{
+			name: "поля изменены, добавлены и удалены",
+			prev: obj{"title": "a", "old": true, "meta": obj{"score": 1}},
+			cur:  obj{"title": "b", "new": "x", "meta": obj{"score": 2}},
+			want: []Change{
+				{Path: "meta.score", Op: OpChanged, Old: 1.0, New: 2.0},
+				{Path: "new", Op: OpAdded, New: "x"},
+				{Path: "old", Op: OpRemoved, Old: true},
+				{Path: "title", Op: OpChanged, Old: "a", New: "b"},
+			},
+		},
+		{
+			name:     "список по ключу не зависит от порядка",
+			prev:     obj{"items": list{obj{"post_id": "1", "points": 5}, obj{"post_id": "2", "points": 7}, obj{"post_id": "3"}}},
+			cur:      obj{"items": list{obj{"post_id": "4"}, obj{"post_id": "2", "points": 8}, obj{"post_id": "1", "points": 5}}},
+			listKeys: []string{"post_id"},
+			want: []Change{
+				{Path: "items[post_id=2].points", Op: OpChanged, Old: 7.0, New: 8.0},
+				{Path: "items[post_id=3]", Op: OpRemoved, Old: obj{"post_id": "3"}},
+				{Path: "items[post_id=4]", Op: OpAdded, New: obj{"post_id": "4"}},
+			},
+		},
+		{
+			name:     "числовой ключ",
+			prev:     obj{"items": list{obj{"id": int32(7), "v": "a"}}},
+			cur:      obj{"items": list{obj{"id": 7, "v": "b"}}},
+			listKeys: []string{"id"},
+			want:     []Change{{Path: "items[id=7].v", Op: OpChanged, Old: "a", New: "b"}},
+		},
+		{
+			name:     "первый ключ есть не у всех - берётся следующий",
+			prev:     obj{"items": list{obj{"id": "a", "url": "u1", "v": 1}, obj{"url": "u2", "v": 2}}},
+			cur:      obj{"items": list{obj{"url": "u2", "v": 3}, obj{"id": "a", "url": "u1", "v": 1}}},
+			listKeys: []string{"id", "url"},
+			want:     []Change{{Path: "items[url=u2].v", Op: OpChanged, Old: 2.0, New: 3.0}},
+		},
+		{
+			name:     "повторяющийся ключ - сравнение по индексу",
+			prev:     obj{"items": list{obj{"id": "a", "v": 1}, obj{"id": "a", "v": 2}}},
+			cur:      obj{"items": list{obj{"id": "a", "v": 1}, obj{"id": "a", "v": 3}, obj{"id": "b"}}},
+			listKeys: []string{"id"},
+			want: []Change{
+				{Path: "items[1].v", Op: OpChanged, Old: 2.0, New: 3.0},
+				{Path: "items[2]", Op: OpAdded, New: obj{"id": "b"}},
+			},
+		},
+		{
+			name:     "ключа нет ни у кого - сравнение по индексу",
+			prev:     obj{"tags": list{"a", "b", "c"}},
+			cur:      obj{"tags": list{"a", "x"}},
+			listKeys: []string{"id"},
+			want: []Change{
+				{Path: "tags[1]", Op: OpChanged, Old: "b", New: "x"},
+				{Path: "tags[2]", Op: OpRemoved, Old: "c"},
+			},
+		},
+		{
+			name:     "повторный обход HN с теми же данными",
+			prev:     stored(hnCrawl()),
+			cur:      hnCrawl(),
+			listKeys: []string{"post_id", "id", "url"},
+		},
+		{
+			name: "смена типа значения",
+			prev: obj{"v": list{1}},
+			cur:  obj{"v": obj{"a": 1}},
+			want: []Change{{Path: "v", Op: OpChanged, Old: list{1.0}, New: obj{"a": 1.0}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Diff(tt.prev, tt.cur, tt.listKeys)
+			if err != nil {
+				t.Fatalf("Diff: %v", err)
+			}
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff:\n got %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchKey(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		a, b list
+		want string
+	}{
+		{name: "пустые списки", keys: []string{"id"}, want: ""},
+		{name: "ключи не заданы", a: list{obj{"id": "1"}}, b: list{obj{"id": "1"}}, want: ""},
+		{
+			name: "ключ у всех элементов",
+			keys: []string{"id"},
+			a:    list{obj{"id": "1"}, obj{"id": "2"}},
+			b:    list{obj{"id": "2"}},
+			want: "id",
+		},
+		{
+			name: "один список пуст",
+			keys: []string{"id"},
+			b:    list{obj{"id": "1"}},
+			want: "id",
+		},
+		{
+			name: "ключа нет у элемента второго списка",
+			keys: []string{"id"},
+			a:    list{obj{"id": "1"}},
+			b:    list{obj{"id": "1"}, obj{"name": "x"}},
+			want: "",
+		},
+		{
+			name: "ключ равен nil",
+			keys: []string{"id"},
+			a:    list{obj{"id": nil}},
+			b:    list{obj{"id": "1"}},
+			want: "",
+		},
+		{
+			name: "дубликат ключа в одном списке",
+			keys: []string{"id", "url"},
+			a:    list{obj{"id": "1", "url": "a"}, obj{"id": "1", "url": "b"}},
+			b:    list{obj{"id": "1", "url": "a"}},
+			want: "url",
+		},
+		{
+			name: "одинаковый ключ в разных списках не дубликат",
+			keys: []string{"id"},
+			a:    list{obj{"id": "1"}},
+			b:    list{obj{"id": "1"}},
+			want: "id",
+		},
+		{
+			name: "список скаляров",
+			keys: []string{"id"},
+			a:    list{"a", "b"},
+			b:    list{"a"},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &differ{listKeys: tt.keys}
+			if got := d.matchKey(tt.a, tt.b); got != tt.want {
+				t.Errorf("matchKey = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	at := time.Date(2025, 3, 1, 12, 30, 0, 123456789, moscow)
+
+	tests := []struct {
+		name string
+		in   obj
+		want interface{}
+	}{
+		{
+			name: "числа становятся float64",
+			in:   obj{"a": int32(1), "b": int64(2), "c": 3, "d": float32(1.5)},
+			want: obj{"a": 1.0, "b": 2.0, "c": 3.0, "d": 1.5},
+		},
+		{
+			name: "время в UTC с точностью до миллисекунд",
+			in:   obj{"t": at},
+			want: obj{"t": "2025-03-01T09:30:00.123Z"},
+		},
+		{
+			name: "время из базы",
+			in:   obj{"t": primitive.NewDateTimeFromTime(at)},
+			want: obj{"t": "2025-03-01T09:30:00.123Z"},
+		},
+		{
+			name: "время внутри списков и объектов",
+			in:   obj{"items": []obj{{"t": at}}},
+			want: obj{"items": list{obj{"t": "2025-03-01T09:30:00.123Z"}}},
+		},
+		{
+			name: "строка не RFC3339 не меняется",
+			in:   obj{"s": "2025-03-01", "n": nil},
+			want: obj{"s": "2025-03-01", "n": nil},
+		},
+		{
+			name: "структуры по JSON-тегам",
+			in: obj{"post": struct {
+				Title string `json:"title"`
+				Count int    `json:"count"`
+			}{"a", 2}},
+			want: obj{"post": obj{"title": "a", "count": 2.0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalize(tt.in)
+			if err != nil {
+				t.Fatalf("normalize: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalize:\n got %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
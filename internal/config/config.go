@@ -4,6 +4,8 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +16,54 @@ type Config struct {
 	DatabaseName   string
 	CollectionName string
 	QueueName      string
+	HTTPAddr       string
+	// MongoIndexMode: ensure | report | off
+	MongoIndexMode string
+
+	QueueBackend     string
+	MemoryQueueSize  int
+	QueueMaxPriority int
+	CrawlStrategy    string
+
+	WorkerCount        int
+	WorkerBuffer       int
+	TaskTimeout        time.Duration
+	ShutdownTimeout    time.Duration
+	MemoryLimitMB      int
+	PauseOnBrowserPool bool
+
+	RetryMaxAttempts int
+	RetryBaseDelay   time.Duration
+	RetryMaxDelay    time.Duration
+
+	VisitedCacheSize     int
+	VisitedRevisitWindow time.Duration
+
+	TrackingParams []string
+
+	BrowserPoolSize int
+	BrowserMaxPages int
+
+	FetchTimeout time.Duration
+	UserAgent    string
+
+	PlansDir string
+
+	FallbackPlan   string
+	UnmatchedLinks string
+
+	ChangesExchange string
+	DiffListKeys    []string
+
+	SchedulerEnabled  bool
+	SchedulerInterval time.Duration
+
+	RobotsUserAgent         string
+	RobotsCacheTTL          time.Duration
+	PolitenessStore         string
+	PolitenessMinInterval   time.Duration
+	PolitenessMaxConcurrent int
+	PolitenessLeaseTTL      time.Duration
 }
 
 func LoadConfig() *Config {
@@ -23,6 +73,53 @@ func LoadConfig() *Config {
 		DatabaseName:   GetEnv("DATABASE_NAME", "parser"),
 		CollectionName: GetEnv("COLLECTION_NAME", "tasks"),
 		QueueName:      GetEnv("QUEUE_NAME", "parser_queue"),
+		HTTPAddr:       ":" + GetEnv("APP_PORT", "8080"),
+		MongoIndexMode: GetEnv("MONGO_INDEX_MODE", "ensure"),
+
+		QueueBackend:     GetEnv("QUEUE_BACKEND", "rabbitmq"),
+		MemoryQueueSize:  GetEnvAsInt("MEMORY_QUEUE_SIZE", 1000),
+		QueueMaxPriority: GetEnvAsInt("QUEUE_MAX_PRIORITY", 10),
+		CrawlStrategy:    GetEnv("CRAWL_STRATEGY", "priority"),
+
+		WorkerCount:        GetEnvAsInt("WORKER_COUNT", 3),
+		WorkerBuffer:       GetEnvAsInt("WORKER_BUFFER", 3),
+		TaskTimeout:        GetEnvAsDuration("TASK_TIMEOUT", 2*time.Minute),
+		ShutdownTimeout:    GetEnvAsDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		MemoryLimitMB:      GetEnvAsInt("MEMORY_LIMIT_MB", 0),
+		PauseOnBrowserPool: GetEnvAsBool("PAUSE_ON_BROWSER_POOL", false),
+
+		RetryMaxAttempts: GetEnvAsInt("RETRY_MAX_ATTEMPTS", 5),
+		RetryBaseDelay:   GetEnvAsDuration("RETRY_BASE_DELAY", 5*time.Second),
+		RetryMaxDelay:    GetEnvAsDuration("RETRY_MAX_DELAY", 10*time.Minute),
+
+		VisitedCacheSize:     GetEnvAsInt("VISITED_CACHE_SIZE", 10000),
+		VisitedRevisitWindow: GetEnvAsDuration("VISITED_REVISIT_WINDOW", 0),
+
+		TrackingParams: GetEnvAsSlice("URLNORM_TRACKING_PARAMS", nil),
+
+		BrowserPoolSize: GetEnvAsInt("BROWSER_POOL_SIZE", 3),
+		BrowserMaxPages: GetEnvAsInt("BROWSER_MAX_PAGES", 100),
+
+		FetchTimeout: GetEnvAsDuration("FETCH_TIMEOUT", 30*time.Second),
+		UserAgent:    GetEnv("USER_AGENT", "go-parser/1.0"),
+
+		PlansDir: GetEnv("PLANS_DIR", "plans"),
+
+		FallbackPlan:   GetEnv("FALLBACK_PLAN", "generic"),
+		UnmatchedLinks: GetEnv("UNMATCHED_LINKS", "skip"),
+
+		ChangesExchange: GetEnv("CHANGES_EXCHANGE", "record_changes"),
+		DiffListKeys:    GetEnvAsSlice("DIFF_LIST_KEYS", []string{"post_id", "id", "url"}),
+
+		SchedulerEnabled:  GetEnvAsBool("SCHEDULER_ENABLED", true),
+		SchedulerInterval: GetEnvAsDuration("SCHEDULER_INTERVAL", 15*time.Second),
+
+		RobotsUserAgent:         GetEnv("ROBOTS_USER_AGENT", "go-parser"),
+		RobotsCacheTTL:          GetEnvAsDuration("ROBOTS_CACHE_TTL", time.Hour),
+		PolitenessStore:         GetEnv("POLITENESS_STORE", "mongo"),
+		PolitenessMinInterval:   GetEnvAsDuration("POLITENESS_MIN_INTERVAL", time.Second),
+		PolitenessMaxConcurrent: GetEnvAsInt("POLITENESS_MAX_CONCURRENT", 2),
+		PolitenessLeaseTTL:      GetEnvAsDuration("POLITENESS_LEASE_TTL", 5*time.Minute),
 	}
 }
 
@@ -38,6 +135,53 @@ func LoadConfigFromFile(filename string) *Config {
 		DatabaseName:   os.Getenv("DATABASE_NAME"),
 		CollectionName: os.Getenv("COLLECTION_NAME"),
 		QueueName:      os.Getenv("QUEUE_NAME"),
+		HTTPAddr:       ":" + os.Getenv("APP_PORT"),
+		MongoIndexMode: GetEnv("MONGO_INDEX_MODE", "ensure"),
+
+		QueueBackend:     GetEnv("QUEUE_BACKEND", "rabbitmq"),
+		MemoryQueueSize:  GetEnvAsInt("MEMORY_QUEUE_SIZE", 1000),
+		QueueMaxPriority: GetEnvAsInt("QUEUE_MAX_PRIORITY", 10),
+		CrawlStrategy:    GetEnv("CRAWL_STRATEGY", "priority"),
+
+		WorkerCount:        GetEnvAsInt("WORKER_COUNT", 3),
+		WorkerBuffer:       GetEnvAsInt("WORKER_BUFFER", 3),
+		TaskTimeout:        GetEnvAsDuration("TASK_TIMEOUT", 2*time.Minute),
+		ShutdownTimeout:    GetEnvAsDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		MemoryLimitMB:      GetEnvAsInt("MEMORY_LIMIT_MB", 0),
+		PauseOnBrowserPool: GetEnvAsBool("PAUSE_ON_BROWSER_POOL", false),
+
+		RetryMaxAttempts: GetEnvAsInt("RETRY_MAX_ATTEMPTS", 5),
+		RetryBaseDelay:   GetEnvAsDuration("RETRY_BASE_DELAY", 5*time.Second),
+		RetryMaxDelay:    GetEnvAsDuration("RETRY_MAX_DELAY", 10*time.Minute),
+
+		VisitedCacheSize:     GetEnvAsInt("VISITED_CACHE_SIZE", 10000),
+		VisitedRevisitWindow: GetEnvAsDuration("VISITED_REVISIT_WINDOW", 0),
+
+		TrackingParams: GetEnvAsSlice("URLNORM_TRACKING_PARAMS", nil),
+
+		BrowserPoolSize: GetEnvAsInt("BROWSER_POOL_SIZE", 3),
+		BrowserMaxPages: GetEnvAsInt("BROWSER_MAX_PAGES", 100),
+
+		FetchTimeout: GetEnvAsDuration("FETCH_TIMEOUT", 30*time.Second),
+		UserAgent:    GetEnv("USER_AGENT", "go-parser/1.0"),
+
+		PlansDir: GetEnv("PLANS_DIR", "plans"),
+
+		FallbackPlan:   GetEnv("FALLBACK_PLAN", "generic"),
+		UnmatchedLinks: GetEnv("UNMATCHED_LINKS", "skip"),
+
+		ChangesExchange: GetEnv("CHANGES_EXCHANGE", "record_changes"),
+		DiffListKeys:    GetEnvAsSlice("DIFF_LIST_KEYS", []string{"post_id", "id", "url"}),
+
+		SchedulerEnabled:  GetEnvAsBool("SCHEDULER_ENABLED", true),
+		SchedulerInterval: GetEnvAsDuration("SCHEDULER_INTERVAL", 15*time.Second),
+
+		RobotsUserAgent:         GetEnv("ROBOTS_USER_AGENT", "go-parser"),
+		RobotsCacheTTL:          GetEnvAsDuration("ROBOTS_CACHE_TTL", time.Hour),
+		PolitenessStore:         GetEnv("POLITENESS_STORE", "mongo"),
+		PolitenessMinInterval:   GetEnvAsDuration("POLITENESS_MIN_INTERVAL", time.Second),
+		PolitenessMaxConcurrent: GetEnvAsInt("POLITENESS_MAX_CONCURRENT", 2),
+		PolitenessLeaseTTL:      GetEnvAsDuration("POLITENESS_LEASE_TTL", 5*time.Minute),
 	}
 }
 
@@ -65,3 +209,27 @@ func GetEnvAsBool(key string, defaultVal bool) bool {
 	}
 	return defaultVal
 }
+
+func GetEnvAsDuration(key string, defaultVal time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if durVal, err := time.ParseDuration(value); err == nil {
+			return durVal
+		}
+	}
+	return defaultVal
+}
+
+func GetEnvAsSlice(key string, defaultVal []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultVal
+	}
+
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,219 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Операторы условий на поле
+const (
+	OpEq     = "eq"
+	OpNe     = "ne"
+	OpGt     = "gt"
+	OpGte    = "gte"
+	OpLt     = "lt"
+	OpLte    = "lte"
+	OpIn     = "in"
+	OpNin    = "nin"
+	OpExists = "exists"
+	OpRegex  = "regex"
+)
+
+// Логические операторы
+const (
+	OpAnd = "and"
+	OpOr  = "or"
+	OpNot = "not"
+)
+
+// Cond - условие фильтра, не зависящее от хранилища. Строится функциями Eq, In, Range,
+// And, Or, Not и переводится в запрос конкретной базы (для MongoDB - mongoCond).
+type Cond interface {
+	isCond()
+}
+
+// FieldCond - условие на значение поля. Field может быть путём через точку: "data.points".
+type FieldCond struct {
+	Field string
+	Op    string
+	Value interface{}
+}
+
+// LogicCond - And/Or над вложенными условиями.
+type LogicCond struct {
+	Op    string
+	Conds []Cond
+}
+
+// NotCond - отрицание условия.
+type NotCond struct {
+	Cond Cond
+}
+
+func (FieldCond) isCond() {}
+func (LogicCond) isCond() {}
+func (NotCond) isCond()   {}
+
+func Eq(field string, v interface{}) Cond  { return FieldCond{Field: field, Op: OpEq, Value: v} }
+func Ne(field string, v interface{}) Cond  { return FieldCond{Field: field, Op: OpNe, Value: v} }
+func Gt(field string, v interface{}) Cond  { return FieldCond{Field: field, Op: OpGt, Value: v} }
+func Gte(field string, v interface{}) Cond { return FieldCond{Field: field, Op: OpGte, Value: v} }
+func Lt(field string, v interface{}) Cond  { return FieldCond{Field: field, Op: OpLt, Value: v} }
+func Lte(field string, v interface{}) Cond { return FieldCond{Field: field, Op: OpLte, Value: v} }
+
+// In - значение поля равно одному из values.
+func In[V any](field string, values ...V) Cond {
+	return FieldCond{Field: field, Op: OpIn, Value: toList(values)}
+}
+
+// Nin - значение поля не равно ни одному из values.
+func Nin[V any](field string, values ...V) Cond {
+	return FieldCond{Field: field, Op: OpNin, Value: toList(values)}
+}
+
+// Range - from <= значение < to. nil оставляет границу открытой, подходит для дат и чисел.
+func Range(field string, from, to interface{}) Cond {
+	var conds []Cond
+	if from != nil {
+		conds = append(conds, Gte(field, from))
+	}
+	if to != nil {
+		conds = append(conds, Lt(field, to))
+	}
+	if len(conds) == 1 {
+		return conds[0]
+	}
+	return And(conds...)
+}
+
+// Regex - значение поля подходит под шаблон. Шаблон передаётся как есть: недоверенный ввод
+// вызывающий экранирует regexp.QuoteMeta сам, как фильтр url_prefix в internal/api/records.go.
+func Regex(field, pattern string) Cond {
+	return FieldCond{Field: field, Op: OpRegex, Value: pattern}
+}
+
+// Exists - поле есть (true) или отсутствует (false) в документе.
+func Exists(field string, exists bool) Cond {
+	return FieldCond{Field: field, Op: OpExists, Value: exists}
+}
+
+func And(conds ...Cond) Cond { return LogicCond{Op: OpAnd, Conds: conds} }
+func Or(conds ...Cond) Cond  { return LogicCond{Op: OpOr, Conds: conds} }
+func Not(c Cond) Cond        { return NotCond{Cond: c} }
+
+// condKey - ключ Filter, под которым Where хранит условие
+const condKey = "$cond"
+
+// Where - фильтр из условий для методов Repository. Поля Filter можно добавлять и после:
+// они объединяются с условиями через And.
+func Where(conds ...Cond) Filter {
+	return Filter{condKey: And(conds...)}
+}
+
+// Cond переводит фильтр в условие. Поле с map - операторы ({"status": {"in": [...]}}),
+// иначе равенство. Неизвестный оператор - ошибка.
+func (f Filter) Cond() (Cond, error) {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	conds := make([]Cond, 0, len(f))
+	for _, k := range keys {
+		switch v := f[k].(type) {
+		case Cond:
+			if k != condKey {
+				return nil, fmt.Errorf("условие для поля %s задаётся оператором, а не Cond", k)
+			}
+			conds = append(conds, v)
+		case map[string]interface{}:
+			ops := make([]string, 0, len(v))
+			for op := range v {
+				ops = append(ops, op)
+			}
+			sort.Strings(ops)
+			for _, op := range ops {
+				conds = append(conds, FieldCond{Field: k, Op: op, Value: v[op]})
+			}
+		default:
+			conds = append(conds, Eq(k, v))
+		}
+	}
+
+	cond := And(conds...)
+	if err := Validate(cond); err != nil {
+		return nil, err
+	}
+	return cond, nil
+}
+
+// Validate проверяет операторы, поля и типы значений условия.
+func Validate(c Cond) error {
+	switch c := c.(type) {
+	case FieldCond:
+		return validateField(c)
+	case LogicCond:
+		if c.Op != OpAnd && c.Op != OpOr {
+			return fmt.Errorf("неизвестный логический оператор %q", c.Op)
+		}
+		if c.Op == OpOr && len(c.Conds) == 0 {
+			return fmt.Errorf("Or без условий")
+		}
+		for _, sub := range c.Conds {
+			if err := Validate(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	case NotCond:
+		if c.Cond == nil {
+			return fmt.Errorf("Not без условия")
+		}
+		return Validate(c.Cond)
+	case nil:
+		return fmt.Errorf("пустое условие фильтра")
+	default:
+		return fmt.Errorf("неизвестное условие фильтра %T", c)
+	}
+}
+
+func validateField(c FieldCond) error {
+	if c.Field == "" || strings.HasPrefix(c.Field, "$") {
+		return fmt.Errorf("недопустимое поле фильтра %q", c.Field)
+	}
+
+	switch c.Op {
+	case OpEq, OpNe, OpGt, OpGte, OpLt, OpLte:
+	case OpIn, OpNin:
+		if c.Value == nil || reflect.TypeOf(c.Value).Kind() != reflect.Slice && reflect.TypeOf(c.Value).Kind() != reflect.Array {
+			return fmt.Errorf("оператор %s поля %s ожидает список, получено %T", c.Op, c.Field, c.Value)
+		}
+	case OpExists:
+		if _, ok := c.Value.(bool); !ok {
+			return fmt.Errorf("оператор exists поля %s ожидает bool, получено %T", c.Field, c.Value)
+		}
+	case OpRegex:
+		pattern, ok := c.Value.(string)
+		if !ok {
+			return fmt.Errorf("оператор regex поля %s ожидает строку, получено %T", c.Field, c.Value)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("оператор regex поля %s: %w", c.Field, err)
+		}
+	default:
+		return fmt.Errorf("неизвестный оператор %q поля %s", c.Op, c.Field)
+	}
+	return nil
+}
+
+func toList[V any](values []V) []interface{} {
+	list := make([]interface{}, len(values))
+	for i, v := range values {
+		list[i] = v
+	}
+	return list
+}
@@ -0,0 +1,204 @@
+package database
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFilterCond(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bson.M
+	}{
+		{
+			name:   "пустой фильтр",
+			filter: nil,
+			want:   bson.M{},
+		},
+		{
+			name:   "равенство",
+			filter: Filter{"url": "https://a", "depth": 2},
+			want:   bson.M{"url": "https://a", "depth": 2},
+		},
+		{
+			name:   "старый формат с операторами",
+			filter: Filter{"status": map[string]interface{}{"in": []string{"pending", "running"}}, "job_id": "j1"},
+			want:   bson.M{"status": bson.M{"$in": []string{"pending", "running"}}, "job_id": "j1"},
+		},
+		{
+			name:   "старый формат, два оператора на поле",
+			filter: Filter{"parsed_at": map[string]interface{}{"lt": 20, "gte": 10}},
+			want: bson.M{"$and": bson.A{
+				bson.M{"parsed_at": bson.M{"$gte": 10}},
+				bson.M{"parsed_at": bson.M{"$lt": 20}},
+			}},
+		},
+		{
+			name:   "exists в старом формате",
+			filter: Filter{"content_hash": map[string]interface{}{"exists": true}},
+			want:   bson.M{"content_hash": bson.M{"$exists": true}},
+		},
+		{
+			name:   "Where",
+			filter: Where(Eq("plan", "hn"), In("domain", "a.com", "b.com")),
+			want:   bson.M{"plan": "hn", "domain": bson.M{"$in": []interface{}{"a.com", "b.com"}}},
+		},
+		{
+			name: "Where с добавленными полями",
+			filter: func() Filter {
+				f := Where(Eq("plan", "hn"))
+				f["job_id"] = "j1"
+				return f
+			}(),
+			want: bson.M{"plan": "hn", "job_id": "j1"},
+		},
+		{
+			name: "Range и поле с тем же именем",
+			filter: func() Filter {
+				f := Where(Range("parsed_at", 10, 20))
+				f["parsed_at"] = map[string]interface{}{"ne": 15}
+				return f
+			}(),
+			want: bson.M{
+				"$and": bson.A{
+					bson.M{"parsed_at": bson.M{"$gte": 10}},
+					bson.M{"parsed_at": bson.M{"$lt": 20}},
+				},
+				"parsed_at": bson.M{"$ne": 15},
+			},
+		},
+		{
+			name:   "Range с одной границей",
+			filter: Where(Range("parsed_at", nil, 20)),
+			want:   bson.M{"parsed_at": bson.M{"$lt": 20}},
+		},
+		{
+			name:   "два Or объединяются через $and",
+			filter: Where(Or(Eq("a", 1), Eq("b", 2)), Or(Eq("c", 3), Eq("d", 4))),
+			want: bson.M{"$and": bson.A{
+				bson.M{"$or": bson.A{bson.M{"a": 1}, bson.M{"b": 2}}},
+				bson.M{"$or": bson.A{bson.M{"c": 3}, bson.M{"d": 4}}},
+			}},
+		},
+		{
+			name:   "Not переводится в $nor",
+			filter: Where(Not(Eq("status", "failed")), Exists("data", true)),
+			want: bson.M{
+				"$nor": bson.A{bson.M{"status": "failed"}},
+				"data": bson.M{"$exists": true},
+			},
+		},
+		{
+			name:   "Not над Or",
+			filter: Where(Not(Or(Eq("a", 1), Regex("url", "^https://")))),
+			want: bson.M{"$nor": bson.A{
+				bson.M{"$or": bson.A{bson.M{"a": 1}, bson.M{"url": bson.M{"$regex": "^https://"}}}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond, err := tt.filter.Cond()
+			if err != nil {
+				t.Fatalf("Cond: %v", err)
+			}
+			if got := mongoCond(cond); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mongoCond:\n got %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterCondErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   string
+	}{
+		{
+			name:   "неизвестный оператор",
+			filter: Filter{"url": map[string]interface{}{"like": "x"}},
+			want:   `неизвестный оператор "like"`,
+		},
+		{
+			name:   "оператор MongoDB вместо поля",
+			filter: Filter{"$where": "sleep(1000)"},
+			want:   "недопустимое поле",
+		},
+		{
+			name:   "оператор MongoDB в старом формате",
+			filter: Filter{"url": map[string]interface{}{"$where": "x"}},
+			want:   "неизвестный оператор",
+		},
+		{
+			name:   "Cond под полем",
+			filter: Filter{"url": Eq("url", "x")},
+			want:   "задаётся оператором",
+		},
+		{
+			name:   "in без списка",
+			filter: Filter{"status": map[string]interface{}{"in": "pending"}},
+			want:   "ожидает список",
+		},
+		{
+			name:   "exists не bool",
+			filter: Filter{"data": map[string]interface{}{"exists": 1}},
+			want:   "ожидает bool",
+		},
+		{
+			name:   "невалидный regex",
+			filter: Where(Regex("url", "(")),
+			want:   "оператор regex",
+		},
+		{
+			name:   "ошибка во вложенном условии",
+			filter: Where(Or(Eq("a", 1), Not(FieldCond{Field: "b", Op: "near"}))),
+			want:   `неизвестный оператор "near"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.filter.Cond()
+			if err == nil {
+				t.Fatal("ожидалась ошибка")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ошибка %q не содержит %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cond    Cond
+		wantErr bool
+	}{
+		{name: "поле", cond: Eq("a", 1)},
+		{name: "путь через точку", cond: Gt("data.points", 10)},
+		{name: "nin", cond: Nin("status", "a", "b")},
+		{name: "пустой And", cond: And()},
+		{name: "пустое условие", cond: nil, wantErr: true},
+		{name: "пустое поле", cond: Eq("", 1), wantErr: true},
+		{name: "пустой Or", cond: Or(), wantErr: true},
+		{name: "Not без условия", cond: Not(nil), wantErr: true},
+		{name: "неизвестный логический оператор", cond: LogicCond{Op: "xor", Conds: []Cond{Eq("a", 1)}}, wantErr: true},
+		{name: "in с nil", cond: FieldCond{Field: "a", Op: OpIn}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.cond)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"strings"
+	"time"
+)
+
+// Порядок поля в индексе
+const (
+	IndexAsc  = "asc"
+	IndexDesc = "desc"
+	IndexText = "text"
+)
+
+// Режимы работы с индексами при запуске
+const (
+	IndexModeEnsure = "ensure" // создать недостающие, о прочих расхождениях сообщить
+	IndexModeReport = "report" // только сообщить о расхождениях
+	IndexModeOff    = "off"
+)
+
+type IndexKey struct {
+	Field string
+	Order string
+}
+
+func Asc(field string) IndexKey  { return IndexKey{Field: field, Order: IndexAsc} }
+func Desc(field string) IndexKey { return IndexKey{Field: field, Order: IndexDesc} }
+func Text(field string) IndexKey { return IndexKey{Field: field, Order: IndexText} }
+
+// Index - объявление индекса коллекции. Ключи идут в порядке полей составного индекса.
+type Index struct {
+	// Name - имя индекса в базе, по нему ищутся расхождения. Пустое - field_1_field_-1, как в MongoDB.
+	Name   string
+	Keys   []IndexKey
+	Unique bool
+	// TTL > 0 - документ удаляется через TTL после времени в поле индекса (только для одного поля)
+	TTL time.Duration
+	// Partial - индексируются только документы под фильтром
+	Partial Filter
+}
+
+// IndexName - имя индекса, объявленное или построенное по ключам.
+func (i Index) IndexName() string {
+	if i.Name != "" {
+		return i.Name
+	}
+	parts := make([]string, 0, len(i.Keys))
+	for _, k := range i.Keys {
+		switch k.Order {
+		case IndexDesc:
+			parts = append(parts, k.Field+"_-1")
+		case IndexText:
+			parts = append(parts, k.Field+"_text")
+		default:
+			parts = append(parts, k.Field+"_1")
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// IndexDrift - расхождения объявленных индексов с коллекцией, перечислены имена индексов.
+type IndexDrift struct {
+	Created []string // созданы при EnsureIndexes
+	Missing []string // объявлены, но в коллекции их нет
+	Changed []string // есть с тем же именем, но другими ключами или опциями
+	Extra   []string // есть в коллекции, но не объявлены
+	// Duplicates - уникальные индексы, которые EnsureIndexes не смог создать из-за
+	// повторяющихся значений в коллекции; они же остаются в Missing
+	Duplicates []string
+}
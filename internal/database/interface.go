@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"iter"
 )
 
 type Entity interface {
@@ -9,6 +10,9 @@ type Entity interface {
 	SetID(string)
 }
 
+// BaseEntity встраивается в сущности с тегом bson:",inline": так ID хранится в _id
+// документа строкой, и по нему работают Get, Update и фильтры по _id. Без inline
+// драйвер пишет вложенный baseentity, такие документы переносит MigrateEmbeddedIDs.
 type BaseEntity struct {
 	ID string `bson:"_id,omitempty" json:"id"`
 }
@@ -22,6 +26,11 @@ type Options struct {
 	Limit  int64
 	Offset int64
 	Sort   map[string]int // поле: 1 (ASC), -1 (DESC)
+	// Fields - возвращаемые поля, пусто - все. Остальные поля сущности остаются нулевыми.
+	Fields []string
+	// After - продолжение выборки после документа предыдущей страницы вместо Offset.
+	// Sort при этом содержит не больше одного поля, равные значения упорядочиваются по _id.
+	After *Keyset
 }
 
 type Repository[T Entity] interface {
@@ -32,9 +41,30 @@ type Repository[T Entity] interface {
 	Create(ctx context.Context, entity T) error
 	Get(ctx context.Context, id string) (T, error)
 	Update(ctx context.Context, entity T) error
+	// UpdateOne обновляет поля первого документа под фильтром; false - документ не найден.
+	// Фильтр с условием на текущее значение даёт атомарный compare-and-set.
+	UpdateOne(ctx context.Context, filter Filter, update map[string]interface{}) (bool, error)
+	// UpdateMany обновляет поля всех документов под фильтром и возвращает число изменённых.
+	UpdateMany(ctx context.Context, filter Filter, update map[string]interface{}) (int64, error)
+	// Increment атомарно прибавляет значения к числовым полям документа id.
+	Increment(ctx context.Context, id string, fields map[string]int64) error
 	Delete(ctx context.Context, id string) error
 
 	Find(ctx context.Context, filter Filter, opts *Options) ([]T, error)
 	FindOne(ctx context.Context, filter Filter) (T, error)
 	Count(ctx context.Context, filter Filter) (int64, error)
+	// Stream отдаёт документы по одному, не загружая выборку в память. Ошибка приходит
+	// последним элементом, выход из range закрывает курсор.
+	Stream(ctx context.Context, filter Filter, opts *Options) iter.Seq2[T, error]
+	// Aggregate выполняет конвейер стадий ({"$match": ...}, {"$group": ...}) и отдаёт
+	// результаты по одному. Filter в $match проверяется и переводится, как в Find.
+	// Документы, где важен порядок ключей ($sort по нескольким полям), передаются как bson.D.
+	Aggregate(ctx context.Context, pipeline []map[string]interface{}) iter.Seq2[map[string]interface{}, error]
+
+	// CheckIndexes сравнивает объявленные индексы с коллекцией, ничего не меняя.
+	CheckIndexes(ctx context.Context, indexes []Index) (*IndexDrift, error)
+	// EnsureIndexes создаёт недостающие индексы. Изменённые и лишние остаются в отчёте:
+	// пересоздание индекса на рабочей базе - решение администратора. Уникальный индекс,
+	// которому мешают дубликаты в данных, тоже только попадает в отчёт (Duplicates).
+	EnsureIndexes(ctx context.Context, indexes []Index) (*IndexDrift, error)
 }
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Keyset - позиция в выборке: значение поля сортировки и _id последнего документа страницы.
+type Keyset struct {
+	Field string
+	Value interface{}
+	ID    string
+}
+
+type keysetToken struct {
+	Field string      `bson:"f"`
+	Value interface{} `bson:"v"`
+	ID    string      `bson:"id"`
+}
+
+// KeysetOf - позиция сразу после entity при сортировке по field ("" - только по _id).
+func KeysetOf[T Entity](entity T, field string) (*Keyset, error) {
+	k := &Keyset{Field: field, ID: entity.GetID()}
+	if field == "" || field == "_id" {
+		return k, nil
+	}
+
+	raw, err := bson.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	v, err := bson.Raw(raw).LookupErr(strings.Split(field, ".")...)
+	if err != nil {
+		// Поля нет в документе - он сортируется как null
+		return k, nil
+	}
+	if err := v.Unmarshal(&k.Value); err != nil {
+		return nil, fmt.Errorf("ошибка чтения поля %s: %w", field, err)
+	}
+	return k, nil
+}
+
+// Encode - непрозрачный токен для клиента. Extended JSON сохраняет тип значения,
+// поэтому дата остаётся датой, а не строкой.
+func (k *Keyset) Encode() (string, error) {
+	data, err := bson.MarshalExtJSON(keysetToken{Field: k.Field, Value: k.Value, ID: k.ID}, true, false)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func DecodeKeyset(token string) (*Keyset, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("неверная позиция выборки: %w", err)
+	}
+	var t keysetToken
+	if err := bson.UnmarshalExtJSON(data, true, &t); err != nil {
+		return nil, fmt.Errorf("неверная позиция выборки: %w", err)
+	}
+	if t.ID == "" {
+		return nil, fmt.Errorf("неверная позиция выборки: нет _id")
+	}
+	return &Keyset{Field: t.Field, Value: t.Value, ID: t.ID}, nil
+}
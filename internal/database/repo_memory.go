@@ -0,0 +1,794 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MemoryRepository - Repository в памяти процесса для тестов кода, работающего с хранилищем.
+// Документы хранятся в BSON, как их записал бы драйвер, фильтры проходят ту же проверку
+// Filter.Cond. Операции выполняются под одной блокировкой, поэтому UpdateOne с условием
+// на текущее значение - такой же compare-and-set, как в MongoDB. Уникальность соблюдается
+// для _id и для уникальных индексов, созданных EnsureIndexes. Aggregate поддерживает
+// только $match.
+type MemoryRepository[T Entity] struct {
+	mu      sync.Mutex
+	docs    map[string]bson.M
+	order   []string
+	indexes map[string]Index
+}
+
+func NewMemoryRepository[T Entity]() *MemoryRepository[T] {
+	return &MemoryRepository[T]{
+		docs:    make(map[string]bson.M),
+		indexes: make(map[string]Index),
+	}
+}
+
+func (r *MemoryRepository[T]) Connect(ctx context.Context) error { return nil }
+func (r *MemoryRepository[T]) Close(ctx context.Context) error   { return nil }
+func (r *MemoryRepository[T]) Ping(ctx context.Context) error    { return nil }
+
+func (r *MemoryRepository[T]) Create(ctx context.Context, entity T) error {
+	if entity.GetID() == "" {
+		entity.SetID(primitive.NewObjectID().Hex())
+	}
+	doc, err := toDoc(entity)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := entity.GetID()
+	if _, exists := r.docs[id]; exists {
+		return duplicateError("_id_", id)
+	}
+	if err := r.checkUnique(id, doc); err != nil {
+		return err
+	}
+	r.docs[id] = doc
+	r.order = append(r.order, id)
+	return nil
+}
+
+func (r *MemoryRepository[T]) Get(ctx context.Context, id string) (T, error) {
+	var entity T
+	if id == "" {
+		return entity, fmt.Errorf("неверный ID: пустое значение")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.docs[id]
+	if !ok {
+		return entity, nil
+	}
+	return fromDoc[T](doc)
+}
+
+func (r *MemoryRepository[T]) Update(ctx context.Context, entity T) error {
+	if entity.GetID() == "" {
+		return fmt.Errorf("неверный ID: пустое значение")
+	}
+	fields, err := toDoc(entity)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.docs[entity.GetID()]
+	if !ok {
+		return fmt.Errorf("запись не найдена")
+	}
+	return r.set(entity.GetID(), doc, fields)
+}
+
+func (r *MemoryRepository[T]) UpdateOne(ctx context.Context, filter Filter, update map[string]interface{}) (bool, error) {
+	cond, err := filter.Cond()
+	if err != nil {
+		return false, err
+	}
+	fields, err := toValues(update)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range r.order {
+		if doc := r.docs[id]; matchCond(doc, cond) {
+			return true, r.set(id, doc, fields)
+		}
+	}
+	return false, nil
+}
+
+func (r *MemoryRepository[T]) UpdateMany(ctx context.Context, filter Filter, update map[string]interface{}) (int64, error) {
+	cond, err := filter.Cond()
+	if err != nil {
+		return 0, err
+	}
+	fields, err := toValues(update)
+	if err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for _, id := range r.order {
+		if doc := r.docs[id]; matchCond(doc, cond) {
+			if err := r.set(id, doc, fields); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *MemoryRepository[T]) Increment(ctx context.Context, id string, fields map[string]int64) error {
+	if id == "" {
+		return fmt.Errorf("неверный ID: пустое значение")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.docs[id]
+	if !ok {
+		return nil
+	}
+	for field, delta := range fields {
+		cur, _ := lookupPath(doc, field)
+		switch n := cur.(type) {
+		case float64:
+			setPath(doc, field, n+float64(delta))
+		default:
+			v, _ := toInt64(cur)
+			setPath(doc, field, v+delta)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository[T]) Delete(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("неверный ID: пустое значение")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.docs[id]; !ok {
+		return fmt.Errorf("запись не найдена")
+	}
+	delete(r.docs, id)
+	for i, v := range r.order {
+		if v == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository[T]) Find(ctx context.Context, filter Filter, opts *Options) ([]T, error) {
+	docs, err := r.query(filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]T, 0, len(docs))
+	for _, doc := range docs {
+		entity, err := fromDoc[T](doc)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, entity)
+	}
+	return results, nil
+}
+
+func (r *MemoryRepository[T]) FindOne(ctx context.Context, filter Filter) (T, error) {
+	var entity T
+	docs, err := r.query(filter, &Options{Limit: 1})
+	if err != nil || len(docs) == 0 {
+		return entity, err
+	}
+	return fromDoc[T](docs[0])
+}
+
+func (r *MemoryRepository[T]) Count(ctx context.Context, filter Filter) (int64, error) {
+	docs, err := r.query(filter, nil)
+	return int64(len(docs)), err
+}
+
+func (r *MemoryRepository[T]) Stream(ctx context.Context, filter Filter, opts *Options) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		results, err := r.Find(ctx, filter, opts)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		for _, entity := range results {
+			if !yield(entity, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (r *MemoryRepository[T]) Aggregate(ctx context.Context, pipeline []map[string]interface{}) iter.Seq2[map[string]interface{}, error] {
+	return func(yield func(map[string]interface{}, error) bool) {
+		filter := Filter{}
+		for _, stage := range pipeline {
+			match, ok := stage["$match"].(Filter)
+			if len(stage) != 1 || !ok {
+				yield(nil, fmt.Errorf("хранилище в памяти поддерживает только стадию $match с Filter: %v", stage))
+				return
+			}
+			filter = Where(mustCond(match), mustCond(filter))
+		}
+
+		docs, err := r.query(filter, nil)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for _, doc := range docs {
+			if !yield(map[string]interface{}(doc), nil) {
+				return
+			}
+		}
+	}
+}
+
+func (r *MemoryRepository[T]) CheckIndexes(ctx context.Context, indexes []Index) (*IndexDrift, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	drift, _, err := r.indexDrift(indexes)
+	return drift, err
+}
+
+func (r *MemoryRepository[T]) EnsureIndexes(ctx context.Context, indexes []Index) (*IndexDrift, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	drift, missing, err := r.indexDrift(indexes)
+	if err != nil || len(missing) == 0 {
+		return drift, err
+	}
+
+	drift.Missing = nil
+	for _, idx := range missing {
+		name := idx.IndexName()
+		if idx.Unique && r.hasDuplicates(idx) {
+			drift.Missing = append(drift.Missing, name)
+			drift.Duplicates = append(drift.Duplicates, name)
+			continue
+		}
+		r.indexes[name] = idx
+		drift.Created = append(drift.Created, name)
+	}
+	return drift, nil
+}
+
+func (r *MemoryRepository[T]) indexDrift(indexes []Index) (*IndexDrift, []Index, error) {
+	drift := &IndexDrift{}
+	var missing []Index
+	declared := make(map[string]bool, len(indexes))
+	for _, idx := range indexes {
+		if err := validateIndex(idx); err != nil {
+			return nil, nil, err
+		}
+		name := idx.IndexName()
+		declared[name] = true
+
+		got, ok := r.indexes[name]
+		switch {
+		case !ok:
+			drift.Missing = append(drift.Missing, name)
+			missing = append(missing, idx)
+		case !reflect.DeepEqual(got, idx):
+			drift.Changed = append(drift.Changed, name)
+		}
+	}
+	for name := range r.indexes {
+		if !declared[name] {
+			drift.Extra = append(drift.Extra, name)
+		}
+	}
+	sort.Strings(drift.Extra)
+	return drift, missing, nil
+}
+
+// query - документы под фильтром в порядке Options. Вызывается без блокировки.
+func (r *MemoryRepository[T]) query(filter Filter, opts *Options) ([]bson.M, error) {
+	cond, err := filter.Cond()
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	sortField, order := "", 1
+	for field, o := range opts.Sort {
+		sortField, order = field, o
+	}
+	if opts.After != nil {
+		if len(opts.Sort) > 1 {
+			return nil, fmt.Errorf("keyset-пагинация поддерживает сортировку только по одному полю")
+		}
+		if opts.After.Field != sortField && !(sortField == "_id" && opts.After.Field == "") {
+			return nil, fmt.Errorf("позиция выборки получена для сортировки по %q, а не по %q", opts.After.Field, sortField)
+		}
+	}
+
+	r.mu.Lock()
+	var docs []bson.M
+	for _, id := range r.order {
+		if doc := r.docs[id]; matchCond(doc, cond) {
+			docs = append(docs, cloneDoc(doc))
+		}
+	}
+	r.mu.Unlock()
+
+	if len(opts.Sort) > 0 || opts.After != nil {
+		fields := make([]string, 0, len(opts.Sort)+1)
+		orders := make([]int, 0, len(opts.Sort)+1)
+		for field, o := range opts.Sort {
+			fields = append(fields, field)
+			orders = append(orders, o)
+		}
+		if sortField != "_id" {
+			fields = append(fields, "_id")
+			orders = append(orders, order)
+		}
+		sort.SliceStable(docs, func(i, j int) bool {
+			for k, field := range fields {
+				a, _ := lookupPath(docs[i], field)
+				b, _ := lookupPath(docs[j], field)
+				if c := compareOrder(a, b); c != 0 {
+					return c*orders[k] < 0
+				}
+			}
+			return false
+		})
+	}
+
+	if opts.After != nil {
+		after, err := normalizeValue(opts.After.Value)
+		if err != nil {
+			return nil, err
+		}
+		kept := docs[:0]
+		for _, doc := range docs {
+			if afterKeyset(doc, sortField, order, after, opts.After.ID) {
+				kept = append(kept, doc)
+			}
+		}
+		docs = kept
+	}
+
+	if opts.Offset > 0 {
+		docs = docs[min(int(opts.Offset), len(docs)):]
+	}
+	if opts.Limit > 0 && int(opts.Limit) < len(docs) {
+		docs = docs[:opts.Limit]
+	}
+
+	if len(opts.Fields) > 0 {
+		for i, doc := range docs {
+			projected := bson.M{"_id": doc["_id"]}
+			for _, field := range opts.Fields {
+				if v, ok := lookupPath(doc, field); ok {
+					setPath(projected, field, v)
+				}
+			}
+			docs[i] = projected
+		}
+	}
+
+	return docs, nil
+}
+
+// set применяет $set к документу id, соблюдая уникальные индексы. Вызывается под блокировкой.
+func (r *MemoryRepository[T]) set(id string, doc, fields bson.M) error {
+	updated := cloneDoc(doc)
+	for k, v := range fields {
+		if k == "_id" {
+			continue
+		}
+		setPath(updated, k, v)
+	}
+	if err := r.checkUnique(id, updated); err != nil {
+		return err
+	}
+	r.docs[id] = updated
+	return nil
+}
+
+func (r *MemoryRepository[T]) checkUnique(id string, doc bson.M) error {
+	for name, idx := range r.indexes {
+		if !idx.Unique || !r.indexed(idx, doc) {
+			continue
+		}
+		for otherID, other := range r.docs {
+			if otherID != id && r.indexed(idx, other) && sameKeys(idx, doc, other) {
+				return duplicateError(name, id)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository[T]) hasDuplicates(idx Index) bool {
+	for id, doc := range r.docs {
+		if !r.indexed(idx, doc) {
+			continue
+		}
+		for otherID, other := range r.docs {
+			if otherID != id && r.indexed(idx, other) && sameKeys(idx, doc, other) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// indexed - попадает ли документ в частичный индекс.
+func (r *MemoryRepository[T]) indexed(idx Index, doc bson.M) bool {
+	if len(idx.Partial) == 0 {
+		return true
+	}
+	cond, err := idx.Partial.Cond()
+	return err == nil && matchCond(doc, cond)
+}
+
+func sameKeys(idx Index, a, b bson.M) bool {
+	for _, k := range idx.Keys {
+		av, _ := lookupPath(a, k.Field)
+		bv, _ := lookupPath(b, k.Field)
+		if !valuesEqual(av, bv) {
+			return false
+		}
+	}
+	return true
+}
+
+func duplicateError(index, id string) error {
+	return mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+		Code:    11000,
+		Message: fmt.Sprintf("E11000 duplicate key error index: %s, _id: %s", index, id),
+	}}}
+}
+
+func mustCond(f Filter) Cond {
+	cond, err := f.Cond()
+	if err != nil {
+		// Фильтры проверяются ещё раз в query, там ошибка и вернётся
+		return FieldCond{Op: "invalid"}
+	}
+	return cond
+}
+
+// matchCond проверяет документ так же, как MongoDB выполнила бы mongoCond(c).
+func matchCond(doc bson.M, c Cond) bool {
+	switch c := c.(type) {
+	case FieldCond:
+		return matchField(doc, c)
+	case LogicCond:
+		if c.Op == OpOr {
+			for _, sub := range c.Conds {
+				if matchCond(doc, sub) {
+					return true
+				}
+			}
+			return false
+		}
+		for _, sub := range c.Conds {
+			if !matchCond(doc, sub) {
+				return false
+			}
+		}
+		return true
+	case NotCond:
+		return !matchCond(doc, c.Cond)
+	}
+	return false
+}
+
+func matchField(doc bson.M, c FieldCond) bool {
+	v, exists := lookupPath(doc, c.Field)
+	want, err := normalizeValue(c.Value)
+	if err != nil {
+		return false
+	}
+
+	switch c.Op {
+	case OpEq:
+		return matchEq(v, exists, want)
+	case OpNe:
+		return !matchEq(v, exists, want)
+	case OpIn, OpNin:
+		list, _ := want.(primitive.A)
+		found := false
+		for _, item := range list {
+			if matchEq(v, exists, item) {
+				found = true
+				break
+			}
+		}
+		return found == (c.Op == OpIn)
+	case OpExists:
+		return exists == want.(bool)
+	case OpRegex:
+		re, err := regexp.Compile(want.(string))
+		if err != nil {
+			return false
+		}
+		return anyElement(v, func(item interface{}) bool {
+			s, ok := item.(string)
+			return ok && re.MatchString(s)
+		})
+	case OpGt, OpGte, OpLt, OpLte:
+		if !exists {
+			return false
+		}
+		return anyElement(v, func(item interface{}) bool {
+			cmp, ok := compareValues(item, want)
+			if !ok {
+				return false
+			}
+			switch c.Op {
+			case OpGt:
+				return cmp > 0
+			case OpGte:
+				return cmp >= 0
+			case OpLt:
+				return cmp < 0
+			default:
+				return cmp <= 0
+			}
+		})
+	}
+	return false
+}
+
+// matchEq - равенство по правилам MongoDB: отсутствующее поле равно null,
+// массив равен значению, если равен целиком или содержит его.
+func matchEq(v interface{}, exists bool, want interface{}) bool {
+	if !exists {
+		return want == nil
+	}
+	if valuesEqual(v, want) {
+		return true
+	}
+	if list, ok := v.(primitive.A); ok {
+		for _, item := range list {
+			if valuesEqual(item, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func anyElement(v interface{}, pred func(interface{}) bool) bool {
+	if list, ok := v.(primitive.A); ok {
+		for _, item := range list {
+			if pred(item) {
+				return true
+			}
+		}
+		return false
+	}
+	return pred(v)
+}
+
+// afterKeyset - документ строго после позиции (value, id) в порядке (field, _id), как keysetFilter.
+func afterKeyset(doc bson.M, field string, order int, value interface{}, id string) bool {
+	docID, _ := doc["_id"].(string)
+	idCmp := strings.Compare(docID, id)
+	if field == "" || field == "_id" {
+		return idCmp*order > 0
+	}
+
+	v, _ := lookupPath(doc, field)
+	if c := compareOrder(v, value); c != 0 {
+		return c*order > 0
+	}
+	return idCmp*order > 0
+}
+
+func valuesEqual(a, b interface{}) bool {
+	if cmp, ok := compareValues(a, b); ok {
+		return cmp == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareValues сравнивает значения одного типа BSON; false - типы несравнимы.
+func compareValues(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, a == nil && b == nil
+	}
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			return compareOrdered(x, y), true
+		}
+		return 0, false
+	}
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case primitive.DateTime:
+		if y, ok := b.(primitive.DateTime); ok {
+			return compareOrdered(x, y), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, true
+			case y:
+				return -1, true
+			default:
+				return 1, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// compareOrder - порядок сортировки: null раньше любых значений, несравнимые типы равны.
+func compareOrder(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	cmp, _ := compareValues(a, b)
+	return cmp
+}
+
+func compareOrdered[V int64 | float64 | primitive.DateTime](a, b V) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func lookupPath(doc bson.M, path string) (interface{}, bool) {
+	var cur interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		m, ok := asMap(cur)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func setPath(doc bson.M, path string, v interface{}) {
+	parts := strings.Split(path, ".")
+	cur := doc
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := asMap(cur[part])
+		if !ok {
+			next = bson.M{}
+		}
+		cur[part] = next
+		cur = next
+	}
+	cur[parts[len(parts)-1]] = v
+}
+
+func asMap(v interface{}) (bson.M, bool) {
+	switch m := v.(type) {
+	case bson.M:
+		return m, true
+	case map[string]interface{}:
+		return m, true
+	case primitive.D:
+		return m.Map(), true
+	}
+	return nil, false
+}
+
+// normalizeValue приводит значение к типам, в которых его вернул бы драйвер:
+// time.Time - к primitive.DateTime, int - к int32/int64, срезы - к primitive.A.
+func normalizeValue(v interface{}) (interface{}, error) {
+	doc, err := toValues(map[string]interface{}{"v": v})
+	if err != nil {
+		return nil, err
+	}
+	return doc["v"], nil
+}
+
+func toValues(fields map[string]interface{}) (bson.M, error) {
+	raw, err := bson.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	err = bson.Unmarshal(raw, &doc)
+	return doc, err
+}
+
+func toDoc(entity interface{}) (bson.M, error) {
+	raw, err := bson.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	err = bson.Unmarshal(raw, &doc)
+	return doc, err
+}
+
+func fromDoc[T Entity](doc bson.M) (T, error) {
+	var entity T
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return entity, err
+	}
+	err = bson.Unmarshal(raw, &entity)
+	return entity, err
+}
+
+func cloneDoc(doc bson.M) bson.M {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return doc
+	}
+	var clone bson.M
+	if err := bson.Unmarshal(raw, &clone); err != nil {
+		return doc
+	}
+	return clone
+}
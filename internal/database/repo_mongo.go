@@ -68,13 +68,13 @@ func (r *MongoRepository[T]) Create(ctx context.Context, entity T) error {
 func (r *MongoRepository[T]) Get(ctx context.Context, id string) (T, error) {
 	var entity T
 
-	objID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return entity, fmt.Errorf("неверный ID: %w", err)
+	if id == "" {
+		return entity, fmt.Errorf("неверный ID: пустое значение")
 	}
 
-	filter := bson.M{"_id": objID}
-	err = r.collection.FindOne(ctx, filter).Decode(&entity)
+	// Create сохраняет _id строкой, поэтому ищем по строке, а не по ObjectID
+	filter := bson.M{"_id": id}
+	err := r.collection.FindOne(ctx, filter).Decode(&entity)
 	if err == mongo.ErrNoDocuments {
 		return entity, nil
 	}
@@ -83,12 +83,11 @@ func (r *MongoRepository[T]) Get(ctx context.Context, id string) (T, error) {
 }
 
 func (r *MongoRepository[T]) Update(ctx context.Context, entity T) error {
-	objID, err := primitive.ObjectIDFromHex(entity.GetID())
-	if err != nil {
-		return fmt.Errorf("неверный ID: %w", err)
+	if entity.GetID() == "" {
+		return fmt.Errorf("неверный ID: пустое значение")
 	}
 
-	filter := bson.M{"_id": objID}
+	filter := bson.M{"_id": entity.GetID()}
 	update := bson.M{"$set": entity}
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
@@ -102,13 +101,41 @@ func (r *MongoRepository[T]) Update(ctx context.Context, entity T) error {
 	return nil
 }
 
-func (r *MongoRepository[T]) Delete(ctx context.Context, id string) error {
-	objID, err := primitive.ObjectIDFromHex(id)
+func (r *MongoRepository[T]) UpdateOne(ctx context.Context, filter Filter, update map[string]interface{}) (bool, error) {
+	mongoFilter, err := r.convertFilter(filter)
 	if err != nil {
-		return fmt.Errorf("неверный ID: %w", err)
+		return false, err
+	}
+	mongoUpdate := bson.M{"$set": update}
+
+	result, err := r.collection.UpdateOne(ctx, mongoFilter, mongoUpdate)
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+func (r *MongoRepository[T]) Increment(ctx context.Context, id string, fields map[string]int64) error {
+	if id == "" {
+		return fmt.Errorf("неверный ID: пустое значение")
+	}
+
+	inc := bson.M{}
+	for field, delta := range fields {
+		inc[field] = delta
 	}
 
-	filter := bson.M{"_id": objID}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": inc})
+	return err
+}
+
+func (r *MongoRepository[T]) Delete(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("неверный ID: пустое значение")
+	}
+
+	filter := bson.M{"_id": id}
 	result, err := r.collection.DeleteOne(ctx, filter)
 	if err != nil {
 		return err
@@ -120,24 +147,11 @@ func (r *MongoRepository[T]) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// Find загружает всю выборку в память; для больших выборок есть Stream.
 func (r *MongoRepository[T]) Find(ctx context.Context, filter Filter, opts *Options) ([]T, error) {
-	mongoFilter := r.convertFilter(filter)
-
-	findOpts := options.Find()
-	if opts != nil {
-		if opts.Limit > 0 {
-			findOpts.SetLimit(opts.Limit)
-		}
-		if opts.Offset > 0 {
-			findOpts.SetSkip(opts.Offset)
-		}
-		if opts.Sort != nil {
-			sortDoc := bson.D{}
-			for field, order := range opts.Sort {
-				sortDoc = append(sortDoc, bson.E{Key: field, Value: order})
-			}
-			findOpts.SetSort(sortDoc)
-		}
+	mongoFilter, findOpts, err := r.findQuery(filter, opts)
+	if err != nil {
+		return nil, err
 	}
 
 	cursor, err := r.collection.Find(ctx, mongoFilter, findOpts)
@@ -157,8 +171,11 @@ func (r *MongoRepository[T]) Find(ctx context.Context, filter Filter, opts *Opti
 func (r *MongoRepository[T]) FindOne(ctx context.Context, filter Filter) (T, error) {
 	var entity T
 
-	mongoFilter := r.convertFilter(filter)
-	err := r.collection.FindOne(ctx, mongoFilter).Decode(&entity)
+	mongoFilter, err := r.convertFilter(filter)
+	if err != nil {
+		return entity, err
+	}
+	err = r.collection.FindOne(ctx, mongoFilter).Decode(&entity)
 	if err == mongo.ErrNoDocuments {
 		return entity, nil
 	}
@@ -167,7 +184,10 @@ func (r *MongoRepository[T]) FindOne(ctx context.Context, filter Filter) (T, err
 }
 
 func (r *MongoRepository[T]) Count(ctx context.Context, filter Filter) (int64, error) {
-	mongoFilter := r.convertFilter(filter)
+	mongoFilter, err := r.convertFilter(filter)
+	if err != nil {
+		return 0, err
+	}
 	return r.collection.CountDocuments(ctx, mongoFilter)
 }
 
@@ -188,38 +208,39 @@ func (r *MongoRepository[T]) CreateMany(ctx context.Context, entities []T) error
 	return err
 }
 
-func (r *MongoRepository[T]) UpdateMany(ctx context.Context, filter Filter, update map[string]interface{}) error {
-	mongoFilter := r.convertFilter(filter)
+func (r *MongoRepository[T]) UpdateMany(ctx context.Context, filter Filter, update map[string]interface{}) (int64, error) {
+	mongoFilter, err := r.convertFilter(filter)
+	if err != nil {
+		return 0, err
+	}
 	mongoUpdate := bson.M{"$set": update}
 
-	_, err := r.collection.UpdateMany(ctx, mongoFilter, mongoUpdate)
-	return err
+	result, err := r.collection.UpdateMany(ctx, mongoFilter, mongoUpdate)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
 }
 
 func (r *MongoRepository[T]) DeleteMany(ctx context.Context, filter Filter) error {
-	mongoFilter := r.convertFilter(filter)
-	_, err := r.collection.DeleteMany(ctx, mongoFilter)
+	mongoFilter, err := r.convertFilter(filter)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteMany(ctx, mongoFilter)
 	return err
 }
 
-func (r *MongoRepository[T]) convertFilter(filter Filter) bson.M {
-	if filter == nil {
-		return bson.M{}
+// convertFilter проверяет фильтр (Filter.Cond) и переводит его в запрос MongoDB.
+func (r *MongoRepository[T]) convertFilter(filter Filter) (bson.M, error) {
+	cond, err := filter.Cond()
+	if err != nil {
+		return nil, err
 	}
+	return mongoCond(cond), nil
+}
 
-	result := bson.M{}
-	for k, v := range filter {
-		switch val := v.(type) {
-		case map[string]interface{}:
-			// Операторы сравнения
-			ops := bson.M{}
-			for op, opVal := range val {
-				ops["$"+op] = opVal // gt -> $gt, lt -> $lt, etc
-			}
-			result[k] = ops
-		default:
-			result[k] = v
-		}
-	}
-	return result
+func IsDuplicate(err error) bool {
+	return mongo.IsDuplicateKeyError(err)
 }
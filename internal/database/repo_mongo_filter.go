@@ -0,0 +1,48 @@
+package database
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// mongoCond переводит проверенное Validate условие в запрос MongoDB.
+func mongoCond(c Cond) bson.M {
+	switch c := c.(type) {
+	case FieldCond:
+		if c.Op == OpEq {
+			return bson.M{c.Field: c.Value}
+		}
+		return bson.M{c.Field: bson.M{"$" + c.Op: c.Value}}
+	case LogicCond:
+		if c.Op == OpOr {
+			return bson.M{"$or": mongoConds(c.Conds)}
+		}
+		return mongoAnd(c.Conds)
+	case NotCond:
+		// $not в MongoDB применим только к операторам поля, $nor - к любому условию
+		return bson.M{"$nor": bson.A{mongoCond(c.Cond)}}
+	}
+	return bson.M{}
+}
+
+func mongoConds(conds []Cond) bson.A {
+	list := make(bson.A, 0, len(conds))
+	for _, c := range conds {
+		list = append(list, mongoCond(c))
+	}
+	return list
+}
+
+// mongoAnd сливает условия в один документ, как писались фильтры до Cond;
+// если ключи повторяются, условия объединяются через $and.
+func mongoAnd(conds []Cond) bson.M {
+	result := bson.M{}
+	for _, c := range conds {
+		for k, v := range mongoCond(c) {
+			if _, dup := result[k]; dup {
+				return bson.M{"$and": mongoConds(conds)}
+			}
+			result[k] = v
+		}
+	}
+	return result
+}
@@ -0,0 +1,272 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoIndex - описание индекса из listIndexes
+type mongoIndex struct {
+	Name    string      `bson:"name"`
+	Key     bson.D      `bson:"key"`
+	Unique  bool        `bson:"unique"`
+	TTL     interface{} `bson:"expireAfterSeconds"`
+	Partial bson.Raw    `bson:"partialFilterExpression"`
+	Weights bson.Raw    `bson:"weights"`
+}
+
+func (r *MongoRepository[T]) CheckIndexes(ctx context.Context, indexes []Index) (*IndexDrift, error) {
+	drift, _, err := r.indexDrift(ctx, indexes)
+	return drift, err
+}
+
+// EnsureIndexes создаёт индексы по одному: уникальный индекс, которому мешают дубликаты
+// в данных, попадает в Duplicates и не останавливает создание остальных.
+func (r *MongoRepository[T]) EnsureIndexes(ctx context.Context, indexes []Index) (*IndexDrift, error) {
+	drift, missing, err := r.indexDrift(ctx, indexes)
+	if err != nil || len(missing) == 0 {
+		return drift, err
+	}
+
+	drift.Missing = nil
+	for _, idx := range missing {
+		model, err := r.indexModel(idx)
+		if err != nil {
+			return drift, err
+		}
+
+		name := idx.IndexName()
+		if _, err := r.collection.Indexes().CreateOne(ctx, model); err != nil {
+			if idx.Unique && mongo.IsDuplicateKeyError(err) {
+				drift.Missing = append(drift.Missing, name)
+				drift.Duplicates = append(drift.Duplicates, name)
+				continue
+			}
+			return drift, fmt.Errorf("ошибка создания индекса %s.%s: %w", r.collName, name, err)
+		}
+		drift.Created = append(drift.Created, name)
+	}
+
+	return drift, nil
+}
+
+// indexDrift сравнивает объявленные индексы с существующими по имени и сигнатуре.
+func (r *MongoRepository[T]) indexDrift(ctx context.Context, indexes []Index) (*IndexDrift, []Index, error) {
+	cursor, err := r.collection.Indexes().List(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка чтения индексов %s: %w", r.collName, err)
+	}
+	var existing []mongoIndex
+	if err := cursor.All(ctx, &existing); err != nil {
+		return nil, nil, fmt.Errorf("ошибка чтения индексов %s: %w", r.collName, err)
+	}
+
+	current := make(map[string]string, len(existing))
+	for _, idx := range existing {
+		current[idx.Name] = existingSignature(idx)
+	}
+
+	drift := &IndexDrift{}
+	var missing []Index
+	declared := make(map[string]bool, len(indexes))
+	for _, idx := range indexes {
+		name := idx.IndexName()
+		declared[name] = true
+
+		want, err := r.indexSignature(idx)
+		if err != nil {
+			return nil, nil, err
+		}
+		got, ok := current[name]
+		switch {
+		case !ok:
+			drift.Missing = append(drift.Missing, name)
+			missing = append(missing, idx)
+		case got != want:
+			drift.Changed = append(drift.Changed, name)
+		}
+	}
+
+	for _, idx := range existing {
+		if idx.Name != "_id_" && !declared[idx.Name] {
+			drift.Extra = append(drift.Extra, idx.Name)
+		}
+	}
+
+	return drift, missing, nil
+}
+
+func (r *MongoRepository[T]) indexModel(idx Index) (mongo.IndexModel, error) {
+	if err := validateIndex(idx); err != nil {
+		return mongo.IndexModel{}, err
+	}
+
+	keys := bson.D{}
+	for _, k := range idx.Keys {
+		keys = append(keys, bson.E{Key: k.Field, Value: mongoKeyOrder(k.Order)})
+	}
+
+	opts := options.Index().SetName(idx.IndexName())
+	if idx.Unique {
+		opts.SetUnique(true)
+	}
+	if idx.TTL > 0 {
+		opts.SetExpireAfterSeconds(int32(idx.TTL.Seconds()))
+	}
+	if len(idx.Partial) > 0 {
+		partial, err := r.convertFilter(idx.Partial)
+		if err != nil {
+			return mongo.IndexModel{}, fmt.Errorf("индекс %s: %w", idx.IndexName(), err)
+		}
+		opts.SetPartialFilterExpression(partial)
+	}
+
+	return mongo.IndexModel{Keys: keys, Options: opts}, nil
+}
+
+func validateIndex(idx Index) error {
+	if len(idx.Keys) == 0 {
+		return fmt.Errorf("индекс %s: не заданы ключи", idx.IndexName())
+	}
+	if idx.TTL > 0 && (len(idx.Keys) != 1 || idx.Keys[0].Order == IndexText) {
+		return fmt.Errorf("индекс %s: TTL допустим только для одного поля", idx.IndexName())
+	}
+	for _, k := range idx.Keys {
+		switch k.Order {
+		case IndexAsc, IndexDesc, IndexText:
+		default:
+			return fmt.Errorf("индекс %s: неизвестный порядок %q поля %s", idx.IndexName(), k.Order, k.Field)
+		}
+	}
+	return nil
+}
+
+func mongoKeyOrder(order string) interface{} {
+	switch order {
+	case IndexDesc:
+		return -1
+	case IndexText:
+		return "text"
+	default:
+		return 1
+	}
+}
+
+// indexSignature - ключи и опции объявленного индекса в том же виде, что existingSignature.
+// Поля текстового индекса MongoDB хранит одним ключом _fts, поэтому они сводятся в text(...).
+func (r *MongoRepository[T]) indexSignature(idx Index) (string, error) {
+	if err := validateIndex(idx); err != nil {
+		return "", err
+	}
+
+	var text []string
+	for _, k := range idx.Keys {
+		if k.Order == IndexText {
+			text = append(text, k.Field)
+		}
+	}
+	sort.Strings(text)
+
+	var keys []string
+	textAdded := false
+	for _, k := range idx.Keys {
+		switch {
+		case k.Order != IndexText:
+			keys = append(keys, k.Field+":"+fmt.Sprint(mongoKeyOrder(k.Order)))
+		case !textAdded:
+			keys = append(keys, "text("+strings.Join(text, ",")+")")
+			textAdded = true
+		}
+	}
+
+	partial := ""
+	if len(idx.Partial) > 0 {
+		filter, err := r.convertFilter(idx.Partial)
+		if err != nil {
+			return "", fmt.Errorf("индекс %s: %w", idx.IndexName(), err)
+		}
+		raw, err := bson.Marshal(filter)
+		if err != nil {
+			return "", fmt.Errorf("индекс %s: %w", idx.IndexName(), err)
+		}
+		partial = canonicalDoc(raw)
+	}
+
+	return signature(keys, idx.Unique, int64(idx.TTL.Seconds()), partial), nil
+}
+
+func existingSignature(idx mongoIndex) string {
+	var keys []string
+	for _, e := range idx.Key {
+		switch e.Key {
+		case "_fts":
+			var fields []string
+			if elems, err := idx.Weights.Elements(); err == nil {
+				for _, el := range elems {
+					fields = append(fields, el.Key())
+				}
+			}
+			sort.Strings(fields)
+			keys = append(keys, "text("+strings.Join(fields, ",")+")")
+		case "_ftsx":
+		default:
+			order := "1"
+			if n, ok := toInt64(e.Value); ok && n < 0 {
+				order = "-1"
+			} else if s, ok := e.Value.(string); ok {
+				order = s
+			}
+			keys = append(keys, e.Key+":"+order)
+		}
+	}
+
+	ttl, _ := toInt64(idx.TTL)
+	partial := ""
+	if len(idx.Partial) > 0 {
+		partial = canonicalDoc(idx.Partial)
+	}
+
+	return signature(keys, idx.Unique, ttl, partial)
+}
+
+func signature(keys []string, unique bool, ttl int64, partial string) string {
+	return fmt.Sprintf("%s|unique=%t|ttl=%d|partial=%s", strings.Join(keys, ","), unique, ttl, partial)
+}
+
+// canonicalDoc - документ с отсортированными ключами: порядок полей фильтра не важен.
+func canonicalDoc(raw bson.Raw) string {
+	elems, err := raw.Elements()
+	if err != nil {
+		return raw.String()
+	}
+
+	parts := make([]string, 0, len(elems))
+	for _, el := range elems {
+		v := el.Value()
+		if doc, ok := v.DocumentOK(); ok {
+			parts = append(parts, el.Key()+":"+canonicalDoc(doc))
+			continue
+		}
+		parts = append(parts, el.Key()+":"+v.String())
+	}
+	sort.Strings(parts)
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
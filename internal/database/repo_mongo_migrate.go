@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MigrateEmbeddedIDs переносит документы, записанные до bson:",inline" у BaseEntity.
+// У них ID лежит в baseentity._id, а _id - ObjectID, созданный MongoDB, поэтому
+// Get/Update/Delete их не находят. Документ пересоздаётся со строковым _id из
+// baseentity._id (без него - hex старого _id). Возвращает число перенесённых документов.
+func (r *MongoRepository[T]) MigrateEmbeddedIDs(ctx context.Context) (int64, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"baseentity": bson.M{"$exists": true}})
+	if err != nil {
+		return 0, fmt.Errorf("ошибка поиска документов %s для миграции: %w", r.collName, err)
+	}
+	defer cursor.Close(ctx)
+
+	var migrated int64
+	for cursor.Next(ctx) {
+		raw := cursor.Current
+		oldID := raw.Lookup("_id")
+
+		id, ok := raw.Lookup("baseentity", "_id").StringValueOK()
+		if !ok || id == "" {
+			oid, isOID := oldID.ObjectIDOK()
+			if !isOID {
+				return migrated, fmt.Errorf("документ %s без строкового ID: %s", r.collName, oldID)
+			}
+			id = oid.Hex()
+		}
+
+		var doc bson.D
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return migrated, err
+		}
+		fixed := bson.D{{Key: "_id", Value: id}}
+		for _, e := range doc {
+			if e.Key != "_id" && e.Key != "baseentity" {
+				fixed = append(fixed, e)
+			}
+		}
+
+		// Дубликат - документ с этим ID уже записан новой версией, старая копия не нужна
+		if _, err := r.collection.InsertOne(ctx, fixed); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return migrated, fmt.Errorf("ошибка переноса документа %s %s: %w", r.collName, id, err)
+		}
+		if _, err := r.collection.DeleteOne(ctx, bson.M{"_id": oldID}); err != nil {
+			return migrated, fmt.Errorf("ошибка удаления старого документа %s %s: %w", r.collName, id, err)
+		}
+		migrated++
+	}
+
+	return migrated, cursor.Err()
+}
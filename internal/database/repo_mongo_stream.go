@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (r *MongoRepository[T]) Stream(ctx context.Context, filter Filter, opts *Options) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		mongoFilter, findOpts, err := r.findQuery(filter, opts)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		cursor, err := r.collection.Find(ctx, mongoFilter, findOpts)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var entity T
+			if err := cursor.Decode(&entity); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(entity, nil) {
+				return
+			}
+		}
+		if err := cursor.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+func (r *MongoRepository[T]) Aggregate(ctx context.Context, pipeline []map[string]interface{}) iter.Seq2[map[string]interface{}, error] {
+	return func(yield func(map[string]interface{}, error) bool) {
+		stages := make(mongo.Pipeline, 0, len(pipeline))
+		for _, stage := range pipeline {
+			if len(stage) != 1 {
+				yield(nil, fmt.Errorf("стадия конвейера должна содержать один оператор: %v", stage))
+				return
+			}
+			for op, v := range stage {
+				if match, ok := v.(Filter); ok && op == "$match" {
+					compiled, err := r.convertFilter(match)
+					if err != nil {
+						yield(nil, err)
+						return
+					}
+					v = compiled
+				}
+				stages = append(stages, bson.D{{Key: op, Value: v}})
+			}
+		}
+
+		cursor, err := r.collection.Aggregate(ctx, stages, options.Aggregate().SetAllowDiskUse(true))
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var doc bson.M
+			if err := cursor.Decode(&doc); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(doc, nil) {
+				return
+			}
+		}
+		if err := cursor.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// findQuery переводит Options в параметры запроса. При сортировке к ней добавляется _id:
+// без него порядок документов с равными значениями не определён и keyset теряет строки.
+func (r *MongoRepository[T]) findQuery(filter Filter, opts *Options) (bson.M, *options.FindOptions, error) {
+	mongoFilter, err := r.convertFilter(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	findOpts := options.Find()
+	if opts == nil {
+		return mongoFilter, findOpts, nil
+	}
+
+	if opts.Limit > 0 {
+		findOpts.SetLimit(opts.Limit)
+	}
+	if opts.Offset > 0 {
+		findOpts.SetSkip(opts.Offset)
+	}
+
+	if len(opts.Fields) > 0 {
+		projection := bson.D{}
+		for _, field := range opts.Fields {
+			projection = append(projection, bson.E{Key: field, Value: 1})
+		}
+		findOpts.SetProjection(projection)
+	}
+
+	sortDoc := bson.D{}
+	sortField, order := "", 1
+	for field, o := range opts.Sort {
+		sortDoc = append(sortDoc, bson.E{Key: field, Value: o})
+		sortField, order = field, o
+	}
+
+	if opts.After != nil {
+		if len(opts.Sort) > 1 {
+			return nil, nil, fmt.Errorf("keyset-пагинация поддерживает сортировку только по одному полю")
+		}
+		if opts.After.Field != sortField && !(sortField == "_id" && opts.After.Field == "") {
+			return nil, nil, fmt.Errorf("позиция выборки получена для сортировки по %q, а не по %q", opts.After.Field, sortField)
+		}
+		after := keysetFilter(sortField, order, opts.After)
+		if len(mongoFilter) > 0 {
+			mongoFilter = bson.M{"$and": bson.A{mongoFilter, after}}
+		} else {
+			mongoFilter = after
+		}
+	}
+
+	if len(sortDoc) > 0 || opts.After != nil {
+		if sortField != "_id" {
+			sortDoc = append(sortDoc, bson.E{Key: "_id", Value: order})
+		}
+		findOpts.SetSort(sortDoc)
+	}
+
+	return mongoFilter, findOpts, nil
+}
+
+// keysetFilter - документы строго после позиции k в порядке (field, _id).
+func keysetFilter(field string, order int, k *Keyset) bson.M {
+	op := "$gt"
+	if order < 0 {
+		op = "$lt"
+	}
+
+	if field == "" || field == "_id" {
+		return bson.M{"_id": bson.M{op: k.ID}}
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{field: bson.M{op: k.Value}},
+		bson.M{field: k.Value, "_id": bson.M{op: k.ID}},
+	}}
+}
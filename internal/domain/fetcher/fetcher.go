@@ -0,0 +1,102 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go_parser/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OptionKey - ключ в task.Options для явного выбора fetcher'а.
+const OptionKey = "fetcher"
+
+type Response struct {
+	StatusCode int
+	Headers    http.Header
+	FinalURL   string
+	Body       []byte
+}
+
+type Fetcher interface {
+	Name() string
+	// Fetch прерывает загрузку по отмене ctx
+	Fetch(ctx context.Context, url string) (*Response, error)
+}
+
+type Registry map[string]Fetcher
+
+func NewRegistry(fetchers ...Fetcher) Registry {
+	r := make(Registry, len(fetchers))
+	for _, f := range fetchers {
+		r[f.Name()] = f
+	}
+	return r
+}
+
+// Select выбирает fetcher из опций задачи, иначе - def (обычно умолчание плана).
+func (r Registry) Select(options map[string]interface{}, def string) (Fetcher, error) {
+	name := def
+	if v, ok := options[OptionKey].(string); ok && v != "" {
+		name = v
+	}
+
+	f, ok := r[name]
+	if !ok {
+		return nil, utils.InvalidTask(fmt.Errorf("fetcher %s not found", name))
+	}
+	return f, nil
+}
+
+// challengeMarkers - признаки страниц проверки на бота, которые отдаются с кодом 200
+var challengeMarkers = [][]byte{
+	[]byte("cf-chl-"),
+	[]byte("captcha-delivery.com"),
+	[]byte("px-captcha"),
+}
+
+// CheckResponse возвращает ошибку с категорией для неуспешного ответа или страницы проверки на бота.
+func CheckResponse(resp *Response) error {
+	switch code := resp.StatusCode; {
+	case code == http.StatusForbidden:
+		return utils.Blocked(fmt.Errorf("сайт отказал в доступе: статус %d", code))
+	case code == http.StatusTooManyRequests:
+		// Сайт просит снизить частоту - повтор после паузы обычно проходит
+		return utils.RetryLater(fmt.Errorf("слишком много запросов: статус %d", code),
+			RetryAfter(resp.Headers, time.Now()))
+	case code == http.StatusRequestTimeout || code >= http.StatusInternalServerError:
+		return utils.Transient(fmt.Errorf("неожиданный статус ответа: %d", code))
+	case code >= http.StatusBadRequest:
+		// 404, 410 и прочие 4xx не исправятся повтором
+		return utils.InvalidTask(fmt.Errorf("неожиданный статус ответа: %d", code))
+	}
+
+	for _, marker := range challengeMarkers {
+		if bytes.Contains(resp.Body, marker) {
+			return utils.Blocked(fmt.Errorf("страница проверки на бота"))
+		}
+	}
+	return nil
+}
+
+// RetryAfter разбирает заголовок Retry-After: число секунд или HTTP-дату.
+// Без заголовка или с неразборчивым значением возвращает 0.
+func RetryAfter(headers http.Header, now time.Time) time.Duration {
+	v := strings.TrimSpace(headers.Get("Retry-After"))
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
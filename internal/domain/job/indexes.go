@@ -0,0 +1,9 @@
+package job
+
+import "go_parser/internal/database"
+
+// Indexes - индексы коллекции jobs.
+var Indexes = []database.Index{
+	{Keys: []database.IndexKey{database.Asc("status"), database.Desc("created_at"), database.Desc("_id")}},
+	{Keys: []database.IndexKey{database.Desc("created_at"), database.Desc("_id")}},
+}
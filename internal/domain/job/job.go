@@ -0,0 +1,106 @@
+package job
+
+import (
+	"go_parser/internal/database"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	StatusRunning   = "running"
+	StatusPaused    = "paused"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Budget ограничивает обход; нулевые значения - без ограничения.
+type Budget struct {
+	MaxPages       int      `bson:"max_pages" json:"max_pages,omitempty"`
+	MaxDurationSec int      `bson:"max_duration_sec" json:"max_duration_sec,omitempty"`
+	MaxDepth       int      `bson:"max_depth" json:"max_depth,omitempty"`
+	AllowedDomains []string `bson:"allowed_domains,omitempty" json:"allowed_domains,omitempty"`
+}
+
+// Counters - число задач задания по состояниям и объём загруженных страниц.
+type Counters struct {
+	Queued    int64 `bson:"queued" json:"queued"`
+	Running   int64 `bson:"running" json:"running"`
+	Done      int64 `bson:"done" json:"done"`
+	Failed    int64 `bson:"failed" json:"failed"`
+	Cancelled int64 `bson:"cancelled" json:"cancelled"`
+	Bytes     int64 `bson:"bytes" json:"bytes"`
+}
+
+// Имена счётчиков в документе для атомарного $inc
+const (
+	CounterQueued    = "counters.queued"
+	CounterRunning   = "counters.running"
+	CounterDone      = "counters.done"
+	CounterFailed    = "counters.failed"
+	CounterCancelled = "counters.cancelled"
+	CounterBytes     = "counters.bytes"
+)
+
+// Job - обход, запущенный с одного seed URL. Все задачи и записи обхода несут его ID.
+type Job struct {
+	database.BaseEntity `bson:",inline"`
+
+	SeedURL  string   `bson:"seed_url" json:"seed_url"`
+	Plan     string   `bson:"plan" json:"plan"`
+	Status   string   `bson:"status" json:"status"`
+	Budget   Budget   `bson:"budget" json:"budget"`
+	Counters Counters `bson:"counters" json:"counters"`
+	// Exhausted - причина, по которой бюджет перестал пропускать новые задачи
+	Exhausted  string     `bson:"exhausted,omitempty" json:"exhausted,omitempty"`
+	Error      string     `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt  time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `bson:"updated_at" json:"updated_at"`
+	FinishedAt *time.Time `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+// Pages - сколько задач создано за время обхода.
+func (j *Job) Pages() int64 {
+	c := j.Counters
+	return c.Queued + c.Running + c.Done + c.Failed + c.Cancelled
+}
+
+// PagesLeft - сколько ещё задач разрешает бюджет; -1 - без ограничения.
+func (j *Job) PagesLeft() int64 {
+	if j.Budget.MaxPages <= 0 {
+		return -1
+	}
+	return max(int64(j.Budget.MaxPages)-j.Pages(), 0)
+}
+
+func (j *Job) Expired(now time.Time) bool {
+	if j.Budget.MaxDurationSec <= 0 {
+		return false
+	}
+	return now.Sub(j.CreatedAt) > time.Duration(j.Budget.MaxDurationSec)*time.Second
+}
+
+// Allows проверяет глубину и домен ссылки по бюджету. Пустая строка - ссылка разрешена.
+func (j *Job) Allows(rawURL string, depth int) string {
+	if j.Budget.MaxDepth > 0 && depth > j.Budget.MaxDepth {
+		return "превышена глубина"
+	}
+
+	if len(j.Budget.AllowedDomains) == 0 {
+		return ""
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "невалидный URL"
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range j.Budget.AllowedDomains {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return ""
+		}
+	}
+	return "домен не разрешён"
+}
@@ -1,20 +1,63 @@
 package plan
 
 import (
+	"context"
+	"fmt"
 	"go_parser/internal/domain/task"
 	"time"
 )
 
+// AutoPlan - имя плана, при котором план выбирается по URL через Plan.Match.
+const AutoPlan = "auto"
+
+func IsAuto(name string) bool {
+	return name == "" || name == AutoPlan
+}
+
 type Plan interface {
 	Name() string
 	Domain() string
 	Match(url string) bool
-	Execute(task *task.Task) (*PlanResult, []FoundURL, error)
+	// Execute должен прерываться по отмене ctx: в нём дедлайн задачи
+	Execute(ctx context.Context, task *task.Task) (*PlanResult, []FoundURL, error)
+}
+
+// Timeouter - план с собственным таймаутом выполнения задачи
+type Timeouter interface {
+	Timeout() time.Duration
+}
+
+// OptionTimeout - ключ в task.Options с таймаутом задачи: секунды числом или строка вида "90s".
+const OptionTimeout = "timeout"
+
+// TimeoutOption возвращает таймаут из опций задачи; 0 - не задан.
+func TimeoutOption(options map[string]interface{}) (time.Duration, error) {
+	var d time.Duration
+	switch v := options[OptionTimeout].(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		d = time.Duration(v * float64(time.Second))
+	case int:
+		d = time.Duration(v) * time.Second
+	case string:
+		var err error
+		if d, err = time.ParseDuration(v); err != nil {
+			return 0, fmt.Errorf("невалидный %s: %w", OptionTimeout, err)
+		}
+	default:
+		return 0, fmt.Errorf("невалидный %s: ожидается число секунд или строка", OptionTimeout)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s должен быть больше нуля", OptionTimeout)
+	}
+	return d, nil
 }
 
 type PlanResult struct {
 	ID         string                 `json:"id" bson:"_id,omitempty"`
 	TaskID     string                 `json:"task_id" bson:"task_id"`
+	JobID      string                 `json:"job_id,omitempty" bson:"job_id,omitempty"`
 	URL        string                 `json:"url" bson:"url"`
 	PlanName   string                 `json:"plan" bson:"plan"`
 	Depth      int                    `json:"depth" bson:"depth"`
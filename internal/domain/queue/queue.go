@@ -1,8 +1,47 @@
 package queue
 
+import (
+	"context"
+	"time"
+)
+
 type WrapperMessage interface {
 	Success()
 	TryAgain()
 	Reject()
+	// Requeue возвращает сообщение в его очередь с задержкой delay
+	Requeue(delay time.Duration) error
 	GetBody() []byte
 }
+
+type Publishing struct {
+	Body    []byte
+	Headers map[string]interface{}
+	// Delay - отложенная доставка, 0 - сразу
+	Delay time.Duration
+	// Priority - приоритет сообщения, больше - раньше; in-memory очередь его не учитывает
+	Priority uint8
+	// Exchange - точка обмена для событий: имя очереди в Publish служит routing key.
+	// In-memory очередь такие сообщения не доставляет - подписчиков у неё нет
+	Exchange string
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, queue string, msg Publishing) error
+}
+
+type Consumer interface {
+	Consume(queue string) (<-chan WrapperMessage, error)
+}
+
+// Broker - очередь задач: RabbitMQ или in-memory реализация для запуска без брокера
+type Broker interface {
+	Publisher
+	Consumer
+	// State - состояние соединения для health-check: connected, reconnecting или closed
+	State() string
+	// StopConsuming перестаёт получать новые сообщения. Каналы Consume закрываются,
+	// когда будут переданы сообщения, полученные до остановки.
+	StopConsuming()
+	Close() error
+}
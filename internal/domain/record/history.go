@@ -0,0 +1,55 @@
+package record
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"go_parser/internal/database"
+	"strconv"
+	"time"
+)
+
+// History - прежняя версия записи, вытесненная изменившимся содержимым страницы.
+type History struct {
+	database.BaseEntity `bson:",inline"`
+
+	RecordID    string                 `json:"record_id" bson:"record_id"`
+	JobID       string                 `json:"job_id,omitempty" bson:"job_id,omitempty"`
+	URL         string                 `json:"url" bson:"url"`
+	PlanName    string                 `json:"plan" bson:"plan"`
+	Version     int                    `json:"version" bson:"version"`
+	ContentHash string                 `json:"content_hash" bson:"content_hash"`
+	Data        map[string]interface{} `json:"data" bson:"data"`
+	// ParsedAt - когда эта версия была видна последний раз
+	ParsedAt   time.Time `json:"parsed_at" bson:"parsed_at"`
+	ArchivedAt time.Time `json:"archived_at" bson:"archived_at"`
+}
+
+// NewHistory архивирует текущую версию записи. ID детерминирован, поэтому
+// повторный архив той же версии не создаёт дубликат.
+func NewHistory(r *Record, archivedAt time.Time) *History {
+	h := &History{
+		RecordID:    r.GetID(),
+		JobID:       r.JobID,
+		URL:         r.URL,
+		PlanName:    r.PlanName,
+		Version:     r.Version,
+		ContentHash: r.ContentHash,
+		Data:        r.Data,
+		ParsedAt:    r.ParsedAt,
+		ArchivedAt:  archivedAt,
+	}
+	h.SetID(r.GetID() + ":" + strconv.Itoa(r.Version))
+	return h
+}
+
+// HashData - sha256 от JSON данных записи. encoding/json сортирует ключи map,
+// поэтому одинаковые данные дают одинаковый хэш.
+func HashData(data map[string]interface{}) (string, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
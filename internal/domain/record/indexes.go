@@ -0,0 +1,29 @@
+package record
+
+import "go_parser/internal/database"
+
+// Indexes - индексы коллекции records. Выборки сортируются по полю и _id, поэтому
+// _id замыкает индексы сортировки. Уникальность (url, plan) действует только
+// для версионируемых записей: записи об ошибках хэша не имеют и могут повторяться.
+var Indexes = []database.Index{
+	{
+		Name:    "url_plan_unique",
+		Keys:    []database.IndexKey{database.Asc("url"), database.Asc("plan")},
+		Unique:  true,
+		Partial: database.Filter{"content_hash": map[string]interface{}{"exists": true}},
+	},
+	{Keys: []database.IndexKey{database.Asc("url")}},
+	{Keys: []database.IndexKey{database.Desc("parsed_at"), database.Desc("_id")}},
+	{Keys: []database.IndexKey{database.Asc("plan"), database.Desc("parsed_at"), database.Desc("_id")}},
+	{Keys: []database.IndexKey{database.Asc("domain"), database.Desc("parsed_at"), database.Desc("_id")}},
+	{Keys: []database.IndexKey{database.Asc("job_id"), database.Desc("parsed_at"), database.Desc("_id")}},
+	{
+		Keys:    []database.IndexKey{database.Asc("error_category"), database.Desc("parsed_at"), database.Desc("_id")},
+		Partial: database.Filter{"error_category": map[string]interface{}{"exists": true}},
+	},
+}
+
+// HistoryIndexes - индексы коллекции record_history.
+var HistoryIndexes = []database.Index{
+	{Keys: []database.IndexKey{database.Asc("record_id"), database.Desc("version")}, Unique: true},
+}
@@ -2,12 +2,15 @@ package record
 
 import (
 	"go_parser/internal/database"
+	"net/url"
+	"strings"
 	"time"
 )
 
 type Record struct {
-	database.BaseEntity // встраиваем BaseEntity с GetID/SetID
+	database.BaseEntity `bson:",inline"` // встраиваем BaseEntity с GetID/SetID
 
+	JobID     string                 `json:"job_id,omitempty" bson:"job_id,omitempty"`
 	URL       string                 `json:"url" bson:"url"`
 	Domain    string                 `json:"domain" bson:"domain"`
 	PlanName  string                 `json:"plan" bson:"plan"`
@@ -17,4 +20,21 @@ type Record struct {
 	ParsedAt  time.Time              `json:"parsed_at" bson:"parsed_at"`
 	CreatedAt time.Time              `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time              `json:"updated_at" bson:"updated_at"`
+
+	// ErrorCategory - категория ошибки (utils.Category) у записей об ошибках
+	ErrorCategory string `json:"error_category,omitempty" bson:"error_category,omitempty"`
+
+	// ContentHash - хэш Data (HashData); пуст у записей об ошибках
+	ContentHash string `json:"content_hash,omitempty" bson:"content_hash,omitempty"`
+	// Version растёт при каждом изменении Data, прежние версии лежат в History
+	Version int `json:"version,omitempty" bson:"version,omitempty"`
+}
+
+// DomainOf - хост URL в нижнем регистре без порта, по нему фильтруются и группируются записи.
+func DomainOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
 }
@@ -0,0 +1,9 @@
+package schedule
+
+import "go_parser/internal/database"
+
+// Indexes - индексы коллекции schedules: планировщик ищет включённые расписания с наступившим next_run.
+var Indexes = []database.Index{
+	{Keys: []database.IndexKey{database.Asc("enabled"), database.Asc("next_run")}},
+	{Keys: []database.IndexKey{database.Asc("name")}},
+}
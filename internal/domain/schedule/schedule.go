@@ -0,0 +1,28 @@
+package schedule
+
+import (
+	"go_parser/internal/database"
+	"go_parser/internal/domain/job"
+	"time"
+)
+
+// Schedule - повторяющийся обход: по cron-выражению ставит seed-задачу и создаёт задание.
+type Schedule struct {
+	database.BaseEntity `bson:",inline"`
+
+	Name     string                 `bson:"name" json:"name"`
+	Cron     string                 `bson:"cron" json:"cron"`
+	URL      string                 `bson:"url" json:"url"`
+	Plan     string                 `bson:"plan" json:"plan"`
+	MaxDepth int                    `bson:"max_depth" json:"max_depth"`
+	Options  map[string]interface{} `bson:"options" json:"options"`
+	Budget   job.Budget             `bson:"budget" json:"budget"`
+	Enabled  bool                   `bson:"enabled" json:"enabled"`
+
+	NextRun   time.Time  `bson:"next_run" json:"next_run"`
+	LastRun   *time.Time `bson:"last_run,omitempty" json:"last_run,omitempty"`
+	LastJobID string     `bson:"last_job_id,omitempty" json:"last_job_id,omitempty"`
+	LastError string     `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `bson:"updated_at" json:"updated_at"`
+}
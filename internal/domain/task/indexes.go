@@ -0,0 +1,11 @@
+package task
+
+import "go_parser/internal/database"
+
+// Indexes - индексы коллекции tasks: выборки задач задания по статусу и список в API.
+var Indexes = []database.Index{
+	{Keys: []database.IndexKey{database.Asc("job_id"), database.Asc("status")}},
+	{Keys: []database.IndexKey{database.Asc("status"), database.Desc("updated_at"), database.Desc("_id")}},
+	{Keys: []database.IndexKey{database.Desc("updated_at"), database.Desc("_id")}},
+	{Keys: []database.IndexKey{database.Asc("url")}},
+}
@@ -1,21 +1,36 @@
 package task
 
 import (
+	"go_parser/internal/database"
 	"time"
+)
 
-	"go.mongodb.org/mongo-driver/bson/primitive"
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusRetrying  = "retrying"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusRejected  = "rejected"
+	// StatusPaused - задача отложена, пока её задание на паузе
+	StatusPaused    = "paused"
+	StatusCancelled = "cancelled"
 )
 
 type Task struct {
-	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"-"`
+	database.BaseEntity `bson:",inline"`
+
 	URL        string                 `bson:"url" json:"url"`
 	Plan       string                 `bson:"plan" json:"plan"`
 	Depth      int                    `bson:"depth" json:"depth"`
 	MaxDepth   int                    `bson:"max_depth" json:"max_depth"`
 	ParentURL  string                 `bson:"parent_url,omitempty" json:"parent_url,omitempty"`
+	JobID      string                 `bson:"job_id,omitempty" json:"job_id,omitempty"`
+	Priority   int                    `bson:"priority" json:"priority,omitempty"`
 	Options    map[string]interface{} `bson:"options" json:"options"`
-	Status     string                 `bson:"status" json:"-"`
-	CreatedAt  time.Time              `bson:"created_at" json:"-"`
-	UpdatedAt  time.Time              `bson:"updated_at" json:"-"`
-	RetryCount int                    `bson:"retry_count" json:"-"`
+	Status     string                 `bson:"status" json:"status,omitempty"`
+	Error      string                 `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt  time.Time              `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time              `bson:"updated_at" json:"updated_at"`
+	RetryCount int                    `bson:"retry_count" json:"retry_count"`
 }
@@ -4,48 +4,146 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"go_parser/internal/database"
+	"go_parser/internal/domain/job"
 	"go_parser/internal/domain/plan"
+	"go_parser/internal/domain/queue"
 	"go_parser/internal/domain/record"
 	"go_parser/internal/domain/task"
+	mq "go_parser/internal/queue"
+	"go_parser/internal/records"
+	"go_parser/internal/urlnorm"
+	"go_parser/internal/utils"
 	"log"
 	"time"
 
-	"github.com/rabbitmq/amqp091-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// errorSaveTimeout - сколько ждать записи ошибки задачи
+const errorSaveTimeout = 10 * time.Second
+
+// RecordStore сохраняет результаты с версионированием и записи об ошибках
+type RecordStore interface {
+	Save(ctx context.Context, r *record.Record) (*records.Saved, error)
+	SaveError(ctx context.Context, r *record.Record) error
+}
+
+type TaskTracker interface {
+	Pending(ctx context.Context, t *task.Task) error
+	Paused(ctx context.Context, t *task.Task) error
+	Unpark(ctx context.Context, t *task.Task) (bool, error)
+	Failed(ctx context.Context, t *task.Task, reason error) error
+	Cancelled(ctx context.Context, t *task.Task) error
+}
+
+// JobBudget - задания обхода: бюджеты для новых ссылок и учёт загруженного объёма
+type JobBudget interface {
+	Get(ctx context.Context, id string) (*job.Job, error)
+	Exhaust(ctx context.Context, id, reason string) error
+	AddBytes(ctx context.Context, id string, n int64) error
+}
+
+type PlanRouter interface {
+	Match(url string) (plan.Plan, bool)
+	Resolve(url string) (plan.Plan, error)
+}
+
+// Политика для найденных ссылок, которым не подошёл ни один план
+const (
+	LinkPolicySkip     = "skip"
+	LinkPolicyFallback = "fallback"
+)
+
+// CheckLinkPolicy проверяет значение политики для ссылок без плана.
+func CheckLinkPolicy(policy string) error {
+	switch policy {
+	case LinkPolicySkip, LinkPolicyFallback:
+		return nil
+	default:
+		return fmt.Errorf("неизвестная политика ссылок без плана %q: ожидается %s или %s",
+			policy, LinkPolicySkip, LinkPolicyFallback)
+	}
+}
+
+type Deduplicator interface {
+	MarkIfNew(ctx context.Context, job, url string) (bool, error)
+	Forget(ctx context.Context, job, url string) error
+}
+
 type Handler struct {
-	repo database.Repository[*record.Record]
-	// Тех долг:
-	// Написать оболочку для очереди
-	queue     *amqp091.Channel
+	records RecordStore
+	tracker TaskTracker
+	jobs    JobBudget
+	visited Deduplicator
+	norm    *urlnorm.Normalizer
+	router  PlanRouter
+	// unmatched - LinkPolicySkip или LinkPolicyFallback
+	unmatched string
+	queue     queue.Publisher
 	queueName string
+	retry     mq.RetryPolicy
+	strategy  CrawlStrategy
 }
 
 func NewHandler(
-	repo database.Repository[*record.Record],
-	queue *amqp091.Channel,
+	records RecordStore,
+	tracker TaskTracker,
+	jobs JobBudget,
+	visited Deduplicator,
+	norm *urlnorm.Normalizer,
+	router PlanRouter,
+	unmatched string,
+	queue queue.Publisher,
 	queueName string,
+	retry mq.RetryPolicy,
+	strategy CrawlStrategy,
 ) *Handler {
 	return &Handler{
-		repo:      repo,
+		records:   records,
+		tracker:   tracker,
+		jobs:      jobs,
+		visited:   visited,
+		norm:      norm,
+		router:    router,
+		unmatched: unmatched,
 		queue:     queue,
 		queueName: queueName,
+		retry:     retry,
+		strategy:  strategy,
 	}
 }
 
-func (h *Handler) HandleResult(result *plan.PlanResult, foundURLs []plan.FoundURL, err error) error {
+// HandleResult сохраняет результат и ставит задачи по найденным ссылкам. ctx несёт дедлайн задачи.
+func (h *Handler) HandleResult(ctx context.Context, result *plan.PlanResult, foundURLs []plan.FoundURL, err error) error {
 	if err != nil {
-		return h.handleError(result, err)
+		return h.handleError(ctx, result, err)
 	}
 
-	if err := h.saveResult(result); err != nil {
-		return fmt.Errorf("ошибка сохранения результата: %w", err)
+	if err := h.saveResult(ctx, result); err != nil {
+		return utils.StorageFailure(fmt.Errorf("ошибка сохранения результата: %w", err))
 	}
 
-	tasks := h.createTasks(result, foundURLs)
-	if err := h.sendTasks(tasks); err != nil {
-		return fmt.Errorf("ошибка отправки задач: %w", err)
+	if err := h.jobs.AddBytes(ctx, result.JobID, int64(len(result.HTML))); err != nil {
+		log.Printf("[ERROR] не удалось обновить счётчики задания %s: %v", result.JobID, err)
+	}
+
+	jb, err := h.jobs.Get(ctx, result.JobID)
+	if err != nil {
+		log.Printf("[ERROR] не удалось прочитать задание %s: %v", result.JobID, err)
+	}
+
+	switch {
+	case jb != nil && jb.Status == job.StatusCancelled:
+		if len(foundURLs) > 0 {
+			log.Printf("[SKIP] %s: задание %s отменено, ссылок отброшено: %d", result.URL, jb.GetID(), len(foundURLs))
+		}
+	case jb != nil && jb.Status == job.StatusPaused:
+		// Задачи сохраняются отложенными и будут опубликованы при снятии паузы
+		h.Park(ctx, result.JobID, h.createTasks(ctx, result, foundURLs, jb))
+	default:
+		if err := h.sendTasks(ctx, h.createTasks(ctx, result, foundURLs, jb)); err != nil {
+			return utils.Transient(fmt.Errorf("ошибка отправки задач: %w", err))
+		}
 	}
 
 	log.Printf("[OK] %s | глубина: %d | ссылок: %d | время: %dms",
@@ -54,86 +152,361 @@ func (h *Handler) HandleResult(result *plan.PlanResult, foundURLs []plan.FoundUR
 	return nil
 }
 
-func (h *Handler) saveResult(result *plan.PlanResult) error {
+func (h *Handler) Submit(ctx context.Context, t *task.Task) error {
+	canonical, err := h.norm.Normalize(t.URL)
+	if err != nil {
+		return err
+	}
+	t.URL = canonical
+
+	if t.GetID() == "" {
+		t.SetID(primitive.NewObjectID().Hex())
+	}
+	if t.JobID == "" {
+		t.JobID = t.GetID()
+	}
+	if t.Priority == 0 {
+		t.Priority = h.strategy.SeedPriority()
+	}
+
+	// Seed всегда принимается, но отмечается, чтобы обратные ссылки на него не дублировались
+	if _, err := h.visited.MarkIfNew(ctx, t.JobID, t.URL); err != nil {
+		log.Printf("[ERROR] не удалось отметить URL %s: %v", t.URL, err)
+	}
+
+	return h.sendTasks(ctx, []*task.Task{t})
+}
+
+func (h *Handler) saveResult(ctx context.Context, result *plan.PlanResult) error {
 	record := &record.Record{
+		JobID:    result.JobID,
 		URL:      result.URL,
+		Domain:   record.DomainOf(result.URL),
 		PlanName: result.PlanName,
 		Depth:    result.Depth,
 		Data:     result.Data,
 		ParsedAt: result.ParsedAt,
 	}
 
-	ctx := context.Background()
-	return h.repo.Create(ctx, record)
+	saved, err := h.records.Save(ctx, record)
+	if err != nil {
+		return err
+	}
+	if saved.Outcome == records.OutcomeChanged {
+		log.Printf("[CHANGED] %s: версия %d", result.URL, saved.Record.Version)
+	}
+	return nil
 }
 
-func (h *Handler) createTasks(result *plan.PlanResult, foundURLs []plan.FoundURL) []*task.Task {
+func (h *Handler) createTasks(ctx context.Context, result *plan.PlanResult, foundURLs []plan.FoundURL, jb *job.Job) []*task.Task {
 	var tasks []*task.Task
 
+	// Без задания (старые задачи) бюджет не ограничен
+	left := int64(-1)
+	if jb != nil {
+		if len(foundURLs) > 0 && jb.Expired(time.Now()) {
+			h.exhaust(ctx, jb, "истекло время обхода")
+			return nil
+		}
+		left = jb.PagesLeft()
+	}
+
 	for _, found := range foundURLs {
-		planName := found.Plan
-		if planName == "" || planName == "auto" {
-			planName = result.PlanName
+		canonical, err := h.norm.Resolve(result.URL, found.URL)
+		if err != nil {
+			log.Printf("[WARN] пропущен URL %s: %v", found.URL, err)
+			continue
+		}
+
+		planName, ok := h.routePlan(found.Plan, canonical)
+		if !ok {
+			log.Printf("[SKIP] %s: нет подходящего плана", canonical)
+			continue
+		}
+
+		depth := result.Depth + 1
+		if jb != nil {
+			if reason := jb.Allows(canonical, depth); reason != "" {
+				log.Printf("[SKIP] %s: %s", canonical, reason)
+				continue
+			}
+		}
+
+		// Ссылку не отмечаем посещённой, пока бюджет не разрешил под неё задачу
+		if left == 0 {
+			h.exhaust(ctx, jb, "достигнут лимит страниц")
+			break
+		}
+
+		isNew, err := h.visited.MarkIfNew(ctx, result.JobID, canonical)
+		if err != nil {
+			log.Printf("[ERROR] не удалось проверить URL %s: %v", canonical, err)
+		} else if !isNew {
+			continue
 		}
 
 		task := &task.Task{
-			URL:       found.URL,
+			URL:       canonical,
 			Plan:      planName,
-			Depth:     result.Depth + 1,
+			Depth:     depth,
 			MaxDepth:  result.MaxDepth,
+			ParentURL: result.URL,
+			JobID:     result.JobID,
+			Priority:  h.strategy.Priority(found.Priority, depth),
 			Options:   found.Context,
-			Status:    "pending",
-			CreatedAt: time.Now(),
 		}
 
 		tasks = append(tasks, task)
+		if left > 0 {
+			left--
+		}
 	}
 
 	return tasks
 }
 
-func (h *Handler) sendTasks(tasks []*task.Task) error {
+// exhaust один раз записывает в задание причину, по которой оно перестало расти.
+// Лимит страниц проверяется по счётчикам без блокировки, поэтому параллельные
+// воркеры могут немного его превысить.
+func (h *Handler) exhaust(ctx context.Context, jb *job.Job, reason string) {
+	if jb.Exhausted != "" {
+		return
+	}
+	jb.Exhausted = reason
+	log.Printf("[BUDGET] задание %s: %s", jb.GetID(), reason)
+	if err := h.jobs.Exhaust(ctx, jb.GetID(), reason); err != nil {
+		log.Printf("[ERROR] не удалось обновить задание %s: %v", jb.GetID(), err)
+	}
+}
+
+// routePlan выбирает план для найденной ссылки. Для "auto" план ищется по URL,
+// а при отсутствии совпадения решает политика unmatched.
+func (h *Handler) routePlan(planName, url string) (string, bool) {
+	if !plan.IsAuto(planName) {
+		return planName, true
+	}
+
+	if p, ok := h.router.Match(url); ok {
+		return p.Name(), true
+	}
+
+	if h.unmatched != LinkPolicyFallback {
+		return "", false
+	}
+
+	p, err := h.router.Resolve(url)
+	if err != nil {
+		return "", false
+	}
+	return p.Name(), true
+}
+
+// sendTasks публикует задачи. При ошибке неотправленные ссылки снимаются с отметки
+// посещённых: повтор родительской задачи поставит их снова.
+func (h *Handler) sendTasks(ctx context.Context, tasks []*task.Task) error {
+	for i, task := range tasks {
+		if err := h.tracker.Pending(ctx, task); err != nil {
+			log.Printf("[ERROR] не удалось сохранить задачу %s: %v", task.URL, err)
+		}
+
+		if err := h.publish(ctx, h.queueName, task, nil, 0); err != nil {
+			err = fmt.Errorf("ошибка отправки задачи %s: %w", task.URL, err)
+			// Задача уже учтена как pending, но в очереди её нет - иначе задание не завершится
+			if trackErr := h.tracker.Failed(ctx, task, err); trackErr != nil {
+				log.Printf("[ERROR] не удалось сохранить задачу %s: %v", task.URL, trackErr)
+			}
+			h.forget(ctx, tasks[i:])
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) forget(ctx context.Context, tasks []*task.Task) {
 	for _, task := range tasks {
-		// Тех долг:
-		// Написать оболочку для очереди
-		body, _ := json.Marshal(task)
-		msg := amqp091.Publishing{
-			ContentType:  "application/json",
-			Body:         body,
-			DeliveryMode: amqp091.Persistent,
-			Headers: amqp091.Table{
-				"x-death-reason": "max_retries_exceeded",
-			},
-		}
-		if err := h.queue.Publish("",
-			h.queueName,
-			false,
-			false,
-			msg); err != nil {
-			return fmt.Errorf("ошибка отправки задачи %s: %w", task.URL, err)
+		if err := h.visited.Forget(ctx, task.JobID, task.URL); err != nil {
+			log.Printf("[ERROR] не удалось снять отметку с URL %s: %v", task.URL, err)
+		}
+	}
+}
+
+// Park откладывает задачи приостановленного задания. Задание могли возобновить или
+// отменить, пока задачи сохранялись, и resume их уже не найдёт, поэтому статус
+// задания перечитывается после сохранения.
+func (h *Handler) Park(ctx context.Context, jobID string, tasks []*task.Task) {
+	if len(tasks) == 0 {
+		return
+	}
+	for _, task := range tasks {
+		if err := h.tracker.Paused(ctx, task); err != nil {
+			log.Printf("[ERROR] не удалось сохранить задачу %s: %v", task.URL, err)
+		}
+	}
+
+	jb, err := h.jobs.Get(ctx, jobID)
+	if err != nil || jb == nil {
+		if err != nil {
+			log.Printf("[ERROR] не удалось прочитать задание %s: %v", jobID, err)
+		}
+		return
+	}
+
+	switch jb.Status {
+	case job.StatusPaused:
+	case job.StatusCancelled:
+		for _, task := range tasks {
+			if err := h.tracker.Cancelled(ctx, task); err != nil {
+				log.Printf("[ERROR] не удалось сохранить задачу %s: %v", task.URL, err)
+			}
 		}
+	default:
+		for _, task := range tasks {
+			if err := h.Resubmit(ctx, task); err != nil {
+				log.Printf("[ERROR] %v", err)
+			}
+		}
+	}
+}
+
+// Resubmit снова публикует задачу, отложенную на время паузы задания. Задачу, которую
+// уже опубликовал кто-то другой, пропускает. Если публикация не удалась, задача снова
+// становится отложенной.
+func (h *Handler) Resubmit(ctx context.Context, t *task.Task) error {
+	claimed, err := h.tracker.Unpark(ctx, t)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления задачи %s: %w", t.URL, err)
+	}
+	if !claimed {
+		return nil
 	}
 
+	if err := h.publish(ctx, h.queueName, t, nil, 0); err != nil {
+		if parkErr := h.tracker.Paused(ctx, t); parkErr != nil {
+			log.Printf("[ERROR] не удалось вернуть задачу %s в отложенные: %v", t.URL, parkErr)
+		}
+		return fmt.Errorf("ошибка отправки задачи %s: %w", t.URL, err)
+	}
 	return nil
 }
 
-func (h *Handler) handleError(result *plan.PlanResult, err error) error {
+// Retry переотправляет упавшую задачу в очередь задержки согласно политике повторов.
+// Если попытки исчерпаны, задача уходит в dead-letter очередь, и deadLettered = true.
+func (h *Handler) Retry(ctx context.Context, t *task.Task, cause error) (deadLettered bool, err error) {
+	if h.retry.Exhausted(t.RetryCount) {
+		if err := h.deadLetter(ctx, t, cause, "max_retries_exceeded"); err != nil {
+			return false, err
+		}
+		log.Printf("[DLQ] %s: попытки исчерпаны (%d)", t.URL, t.RetryCount+1)
+		return true, nil
+	}
+
+	headers := errorHeaders(t, cause)
+
+	t.RetryCount++
+	headers["x-retry-count"] = int32(t.RetryCount)
+	delay := h.retry.Backoff(t.RetryCount)
+	// Retry-After сайта соблюдается в пределах самой длинной очереди задержки
+	if after := min(utils.RetryAfterOf(cause), h.retry.MaxDelay); after > delay {
+		delay = after
+	}
+
+	if err := h.publish(ctx, h.queueName, t, headers, delay); err != nil {
+		t.RetryCount--
+		return false, fmt.Errorf("ошибка повторной отправки задачи %s: %w", t.URL, err)
+	}
+	log.Printf("[RETRY] %s: попытка %d через %s", t.URL, t.RetryCount+1, delay)
+
+	return false, nil
+}
+
+// DeadLetter отправляет задачу в dead-letter очередь без повторов: ошибка не исправится
+// сама, но задачу можно переиграть после разбора.
+func (h *Handler) DeadLetter(ctx context.Context, t *task.Task, cause error) error {
+	if err := h.deadLetter(ctx, t, cause, string(utils.CategoryOf(cause))); err != nil {
+		return err
+	}
+	log.Printf("[DLQ] %s: %v", t.URL, cause)
+	return nil
+}
+
+// DeadLetterRaw сохраняет в dead-letter очереди сообщение, которое не разобралось в задачу,
+// чтобы его можно было изучить, а не потерять при отклонении.
+func (h *Handler) DeadLetterRaw(ctx context.Context, body []byte, cause error) error {
+	msg := queue.Publishing{
+		Body: body,
+		Headers: map[string]interface{}{
+			"x-death-reason":   string(utils.CategoryInvalidTask),
+			"x-last-error":     cause.Error(),
+			"x-error-category": string(utils.CategoryInvalidTask),
+		},
+	}
+	if err := h.queue.Publish(ctx, mq.DeadLetterQueueName(h.queueName), msg); err != nil {
+		return fmt.Errorf("ошибка отправки сообщения в dead-letter очередь: %w", err)
+	}
+	log.Printf("[DLQ] неразобранное сообщение: %v", cause)
+	return nil
+}
+
+func (h *Handler) deadLetter(ctx context.Context, t *task.Task, cause error, reason string) error {
+	headers := errorHeaders(t, cause)
+	headers["x-death-reason"] = reason
+	if err := h.publish(ctx, mq.DeadLetterQueueName(h.queueName), t, headers, 0); err != nil {
+		return fmt.Errorf("ошибка отправки задачи %s в dead-letter очередь: %w", t.URL, err)
+	}
+	return nil
+}
+
+func errorHeaders(t *task.Task, cause error) map[string]interface{} {
+	headers := map[string]interface{}{
+		"x-retry-count": int32(t.RetryCount),
+	}
+	if cause != nil {
+		headers["x-last-error"] = cause.Error()
+		headers["x-error-category"] = string(utils.CategoryOf(cause))
+	}
+	return headers
+}
+
+func (h *Handler) publish(ctx context.Context, queueName string, t *task.Task, headers map[string]interface{}, delay time.Duration) error {
+	body, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	msg := queue.Publishing{
+		Body:     body,
+		Headers:  headers,
+		Delay:    delay,
+		Priority: uint8(min(max(t.Priority, 0), h.strategy.MaxPriority)),
+	}
+
+	return h.queue.Publish(ctx, queueName, msg)
+}
+
+func (h *Handler) handleError(ctx context.Context, result *plan.PlanResult, err error) error {
+	category := utils.CategoryOf(err)
 	errorRecord := &record.Record{
+		JobID:    result.JobID,
 		URL:      result.URL,
+		Domain:   record.DomainOf(result.URL),
 		PlanName: result.PlanName,
 		Depth:    result.Depth,
 		ParsedAt: time.Now(),
 		Data: map[string]interface{}{
 			"error": err.Error(),
 		},
+		ErrorCategory: string(category),
 	}
 
-	ctx := context.Background()
-	if saveErr := h.repo.Create(ctx, errorRecord); saveErr != nil {
+	// Дедлайн задачи к этому моменту может уже истечь, а ошибку записать нужно
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), errorSaveTimeout)
+	defer cancel()
+	if saveErr := h.records.SaveError(ctx, errorRecord); saveErr != nil {
 		log.Printf("[ERROR] не удалось сохранить ошибку: %v", saveErr)
 	}
 
-	log.Printf("[ERROR] %s: %v", result.URL, err)
+	log.Printf("[ERROR] %s (%s): %v", result.URL, category, err)
 
 	return err
 }
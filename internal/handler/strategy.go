@@ -0,0 +1,54 @@
+package handler
+
+import "fmt"
+
+// Стратегии обхода: в каком порядке брать задачи из очереди
+const (
+	// StrategyBFS - сначала неглубокие страницы
+	StrategyBFS = "bfs"
+	// StrategyDFS - сначала глубокие страницы
+	StrategyDFS = "dfs"
+	// StrategyPriority - по приоритету ссылки из плана (FoundURL.Priority)
+	StrategyPriority = "priority"
+)
+
+// CrawlStrategy переводит глубину и приоритет ссылки в приоритет сообщения 0..MaxPriority.
+// AMQP допускает приоритеты до 255, RabbitMQ рекомендует не больше 10.
+type CrawlStrategy struct {
+	Name        string
+	MaxPriority int
+}
+
+// NewCrawlStrategy возвращает ошибку для неизвестной стратегии: опечатка в настройке
+// иначе молча превратилась бы в обход по приоритету.
+func NewCrawlStrategy(name string, maxPriority int) (CrawlStrategy, error) {
+	switch name {
+	case StrategyBFS, StrategyDFS, StrategyPriority:
+	default:
+		return CrawlStrategy{}, fmt.Errorf("неизвестная стратегия обхода %q: ожидается %s, %s или %s",
+			name, StrategyBFS, StrategyDFS, StrategyPriority)
+	}
+
+	return CrawlStrategy{
+		Name:        name,
+		MaxPriority: min(max(maxPriority, 0), 255),
+	}, nil
+}
+
+func (s CrawlStrategy) Priority(linkPriority, depth int) int {
+	var p int
+	switch s.Name {
+	case StrategyBFS:
+		p = s.MaxPriority - depth
+	case StrategyDFS:
+		p = depth
+	default:
+		p = linkPriority
+	}
+	return min(max(p, 0), s.MaxPriority)
+}
+
+// SeedPriority - приоритет задачи, отправленной через API: в BFS и priority стартовые страницы идут первыми.
+func (s CrawlStrategy) SeedPriority() int {
+	return s.Priority(s.MaxPriority, 0)
+}
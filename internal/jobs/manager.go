@@ -0,0 +1,276 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"go_parser/internal/database"
+	"go_parser/internal/domain/job"
+	"go_parser/internal/domain/task"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidState - операция недоступна в текущем статусе задания
+var ErrInvalidState = errors.New("операция недоступна в текущем статусе задания")
+
+// inFlight - статусы задач, которые ещё могут выполниться
+var inFlight = []string{task.StatusPending, task.StatusRunning, task.StatusRetrying, task.StatusPaused}
+
+// Manager создаёт задания, ведёт их счётчики и завершает, когда задач в работе не осталось.
+type Manager struct {
+	repo  database.Repository[*job.Job]
+	tasks database.Repository[*task.Task]
+}
+
+func NewManager(repo database.Repository[*job.Job], tasks database.Repository[*task.Task]) *Manager {
+	return &Manager{
+		repo:  repo,
+		tasks: tasks,
+	}
+}
+
+// Start создаёт задание для seed-задачи и проставляет ей JobID.
+func (m *Manager) Start(ctx context.Context, seed *task.Task, budget job.Budget) (*job.Job, error) {
+	now := time.Now()
+	j := &job.Job{
+		SeedURL:   seed.URL,
+		Plan:      seed.Plan,
+		Status:    job.StatusRunning,
+		Budget:    budget,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	j.SetID(primitive.NewObjectID().Hex())
+
+	if err := m.repo.Create(ctx, j); err != nil {
+		return nil, err
+	}
+
+	seed.JobID = j.GetID()
+	return j, nil
+}
+
+// Get возвращает nil без ошибки, если задания нет: задачи, созданные до появления заданий, его не имеют.
+func (m *Manager) Get(ctx context.Context, id string) (*job.Job, error) {
+	if id == "" {
+		return nil, nil
+	}
+	return m.repo.Get(ctx, id)
+}
+
+func (m *Manager) Fail(ctx context.Context, id string, reason error) error {
+	now := time.Now()
+	_, err := m.repo.UpdateOne(ctx,
+		database.Filter{"_id": id, "status": job.StatusRunning},
+		map[string]interface{}{
+			"status":      job.StatusFailed,
+			"error":       reason.Error(),
+			"finished_at": now,
+			"updated_at":  now,
+		},
+	)
+	return err
+}
+
+// Exhaust отмечает, что бюджет задания перестал пропускать новые задачи.
+func (m *Manager) Exhaust(ctx context.Context, id, reason string) error {
+	_, err := m.repo.UpdateOne(ctx,
+		database.Filter{"_id": id},
+		map[string]interface{}{
+			"exhausted":  reason,
+			"updated_at": time.Now(),
+		},
+	)
+	return err
+}
+
+func (m *Manager) AddBytes(ctx context.Context, id string, n int64) error {
+	if id == "" || n <= 0 {
+		return nil
+	}
+	return m.repo.Increment(ctx, id, map[string]int64{job.CounterBytes: n})
+}
+
+// Transition переносит задачу между счётчиками задания. После перехода в конечный
+// статус проверяет, остались ли задачи в работе, и если нет - завершает задание.
+func (m *Manager) Transition(ctx context.Context, id, from, to string) error {
+	if id == "" {
+		return nil
+	}
+
+	if err := m.move(ctx, id, from, to); err != nil {
+		return err
+	}
+
+	if !isFinal(to) {
+		return nil
+	}
+	return m.complete(ctx, id)
+}
+
+// move переносит одну задачу из счётчика статуса from в счётчик статуса to.
+func (m *Manager) move(ctx context.Context, id, from, to string) error {
+	delta := make(map[string]int64)
+	if c := counterFor(from); c != "" {
+		delta[c]--
+	}
+	if c := counterFor(to); c != "" {
+		delta[c]++
+	}
+	for c, d := range delta {
+		if d == 0 {
+			delete(delta, c)
+		}
+	}
+
+	if len(delta) == 0 {
+		return nil
+	}
+	return m.repo.Increment(ctx, id, delta)
+}
+
+// Pause останавливает выдачу задач задания: воркеры откладывают их до Resume.
+// Уже выполняющиеся задачи доработают, найденные ими ссылки тоже будут отложены.
+func (m *Manager) Pause(ctx context.Context, id string) error {
+	return m.setStatus(ctx, id, []string{job.StatusRunning}, job.StatusPaused)
+}
+
+// Resume снимает задание с паузы и возвращает отложенные задачи, которые нужно снова опубликовать.
+// Для работающего задания возвращает задачи, оставшиеся отложенными после неудачной публикации.
+func (m *Manager) Resume(ctx context.Context, id string) ([]*task.Task, error) {
+	err := m.setStatus(ctx, id, []string{job.StatusPaused}, job.StatusRunning)
+	if errors.Is(err, ErrInvalidState) {
+		jb, getErr := m.repo.Get(ctx, id)
+		if getErr != nil {
+			return nil, getErr
+		}
+		if jb == nil || jb.Status != job.StatusRunning {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	parked, err := m.tasks.Find(ctx, database.Filter{"job_id": id, "status": task.StatusPaused}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Пока задание стояло, все задачи могли завершиться
+	if len(parked) == 0 {
+		return nil, m.complete(ctx, id)
+	}
+	return parked, nil
+}
+
+// Cancel завершает задание и снимает все его задачи, которые ещё не начали выполняться.
+// Их сообщения, оставшиеся в очереди, воркеры отбросят. Каждая задача снимается
+// compare-and-set по статусу, как в трекере: задачу, которую воркер в этот момент
+// перевёл в running, отмена не трогает, и счётчики не сдвигаются дважды.
+func (m *Manager) Cancel(ctx context.Context, id string) error {
+	if err := m.setStatus(ctx, id, []string{job.StatusRunning, job.StatusPaused}, job.StatusCancelled); err != nil {
+		return err
+	}
+
+	for tk, err := range m.tasks.Stream(ctx,
+		database.Where(
+			database.Eq("job_id", id),
+			database.In("status", task.StatusPending, task.StatusRetrying, task.StatusPaused),
+		),
+		&database.Options{Fields: []string{"_id", "status"}},
+	) {
+		if err != nil {
+			return err
+		}
+
+		ok, err := m.tasks.UpdateOne(ctx,
+			database.Filter{"_id": tk.GetID(), "status": tk.Status},
+			map[string]interface{}{
+				"status":     task.StatusCancelled,
+				"updated_at": time.Now(),
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// Статус уже сменил трекер - счётчики перенёс он
+			continue
+		}
+		if err := m.move(ctx, id, tk.Status, task.StatusCancelled); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) setStatus(ctx context.Context, id string, from []string, to string) error {
+	update := map[string]interface{}{
+		"status":     to,
+		"updated_at": time.Now(),
+	}
+	if to == job.StatusCancelled {
+		update["finished_at"] = update["updated_at"]
+	}
+
+	ok, err := m.repo.UpdateOne(ctx,
+		database.Filter{"_id": id, "status": map[string]interface{}{"in": from}},
+		update,
+	)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidState
+	}
+	return nil
+}
+
+func (m *Manager) complete(ctx context.Context, id string) error {
+	remaining, err := m.tasks.Count(ctx, database.Filter{
+		"job_id": id,
+		"status": map[string]interface{}{"in": inFlight},
+	})
+	if err != nil || remaining > 0 {
+		return err
+	}
+
+	now := time.Now()
+	_, err = m.repo.UpdateOne(ctx,
+		database.Filter{"_id": id, "status": job.StatusRunning},
+		map[string]interface{}{
+			"status":      job.StatusCompleted,
+			"finished_at": now,
+			"updated_at":  now,
+		},
+	)
+	return err
+}
+
+func counterFor(status string) string {
+	switch status {
+	case task.StatusPending, task.StatusRetrying, task.StatusPaused:
+		return job.CounterQueued
+	case task.StatusRunning:
+		return job.CounterRunning
+	case task.StatusSucceeded:
+		return job.CounterDone
+	case task.StatusFailed, task.StatusRejected:
+		return job.CounterFailed
+	case task.StatusCancelled:
+		return job.CounterCancelled
+	default:
+		return ""
+	}
+}
+
+func isFinal(status string) bool {
+	switch status {
+	case task.StatusSucceeded, task.StatusFailed, task.StatusRejected, task.StatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,381 @@
+package plans
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go_parser/internal/domain/fetcher"
+	"go_parser/internal/domain/plan"
+	"go_parser/internal/domain/task"
+	"go_parser/internal/urlnorm"
+	"go_parser/internal/utils"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+type PlanSpec struct {
+	Name    string `json:"name" yaml:"name"`
+	Domain  string `json:"domain" yaml:"domain"`
+	Fetcher string `json:"fetcher" yaml:"fetcher"`
+	// Timeout - таймаут задачи плана, например "90s"; пусто - общий TASK_TIMEOUT
+	Timeout string      `json:"timeout" yaml:"timeout"`
+	Match   []string    `json:"match" yaml:"match"`
+	List    string      `json:"list" yaml:"list"`
+	Fields  []FieldSpec `json:"fields" yaml:"fields"`
+	Links   []LinkSpec  `json:"links" yaml:"links"`
+}
+
+type FieldSpec struct {
+	Name     string `json:"name" yaml:"name"`
+	Selector string `json:"selector" yaml:"selector"`
+	Attr     string `json:"attr" yaml:"attr"`
+	Type     string `json:"type" yaml:"type"`
+	Layout   string `json:"layout" yaml:"layout"`
+	Multiple bool   `json:"multiple" yaml:"multiple"`
+	Required bool   `json:"required" yaml:"required"`
+}
+
+type LinkSpec struct {
+	Selector string                 `json:"selector" yaml:"selector"`
+	Attr     string                 `json:"attr" yaml:"attr"`
+	Match    string                 `json:"match" yaml:"match"`
+	Type     string                 `json:"type" yaml:"type"`
+	Priority int                    `json:"priority" yaml:"priority"`
+	Plan     string                 `json:"plan" yaml:"plan"`
+	Context  map[string]interface{} `json:"context" yaml:"context"`
+}
+
+type compiledField struct {
+	FieldSpec
+	sel *selector
+}
+
+type compiledLink struct {
+	LinkSpec
+	sel   *selector
+	match *regexp.Regexp
+}
+
+// DeclarativePlan - план, целиком описанный PlanSpec: селекторы полей и правила перехода по ссылкам.
+type DeclarativePlan struct {
+	spec     PlanSpec
+	match    []*regexp.Regexp
+	list     *selector
+	fields   []compiledField
+	links    []compiledLink
+	fetchers fetcher.Registry
+	norm     *urlnorm.Normalizer
+	timeout  time.Duration
+}
+
+func NewDeclarativePlan(spec PlanSpec, fetchers fetcher.Registry, norm *urlnorm.Normalizer) (*DeclarativePlan, error) {
+	if spec.Name == "" {
+		return nil, fmt.Errorf("не задано имя плана")
+	}
+	if spec.Domain == "" && len(spec.Match) == 0 {
+		return nil, fmt.Errorf("план %s: нужно указать domain или match", spec.Name)
+	}
+	if spec.Fetcher == "" {
+		spec.Fetcher = "http"
+	}
+	if _, ok := fetchers[spec.Fetcher]; !ok {
+		return nil, fmt.Errorf("план %s: fetcher %s not found", spec.Name, spec.Fetcher)
+	}
+
+	p := &DeclarativePlan{
+		spec:     spec,
+		fetchers: fetchers,
+		norm:     norm,
+	}
+
+	if spec.Timeout != "" {
+		timeout, err := time.ParseDuration(spec.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("план %s: невалидный timeout %q", spec.Name, spec.Timeout)
+		}
+		p.timeout = timeout
+	}
+
+	for _, pattern := range spec.Match {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("план %s: невалидный match %q: %w", spec.Name, pattern, err)
+		}
+		p.match = append(p.match, re)
+	}
+
+	var err error
+	if p.list, err = compileSelector(spec.List); err != nil {
+		return nil, fmt.Errorf("план %s: list: %w", spec.Name, err)
+	}
+
+	for _, f := range spec.Fields {
+		if f.Name == "" {
+			return nil, fmt.Errorf("план %s: у поля не задано имя", spec.Name)
+		}
+		if !validFieldTypes[f.Type] {
+			return nil, fmt.Errorf("план %s: поле %s: неизвестный тип %q", spec.Name, f.Name, f.Type)
+		}
+		sel, err := compileSelector(f.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("план %s: поле %s: %w", spec.Name, f.Name, err)
+		}
+		p.fields = append(p.fields, compiledField{FieldSpec: f, sel: sel})
+	}
+
+	for i, l := range spec.Links {
+		if l.Selector == "" {
+			return nil, fmt.Errorf("план %s: ссылка %d: не задан selector", spec.Name, i)
+		}
+		sel, err := compileSelector(l.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("план %s: ссылка %d: %w", spec.Name, i, err)
+		}
+		cl := compiledLink{LinkSpec: l, sel: sel}
+		if cl.Attr == "" {
+			cl.Attr = "href"
+		}
+		if cl.Plan == "" {
+			cl.Plan = spec.Name
+		}
+		if l.Match != "" {
+			if cl.match, err = regexp.Compile(l.Match); err != nil {
+				return nil, fmt.Errorf("план %s: ссылка %d: невалидный match: %w", spec.Name, i, err)
+			}
+		}
+		p.links = append(p.links, cl)
+	}
+
+	return p, nil
+}
+
+func (p *DeclarativePlan) Name() string {
+	return p.spec.Name
+}
+
+func (p *DeclarativePlan) Domain() string {
+	return p.spec.Domain
+}
+
+// Timeout - таймаут из спецификации; 0 - не задан.
+func (p *DeclarativePlan) Timeout() time.Duration {
+	return p.timeout
+}
+
+func (p *DeclarativePlan) Match(rawURL string) bool {
+	if len(p.match) > 0 {
+		for _, re := range p.match {
+			if re.MatchString(rawURL) {
+				return true
+			}
+		}
+		return false
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	domain := strings.ToLower(p.spec.Domain)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+func (p *DeclarativePlan) Execute(ctx context.Context, task *task.Task) (*plan.PlanResult, []plan.FoundURL, error) {
+	started := time.Now()
+
+	f, err := p.fetchers.Select(task.Options, p.spec.Fetcher)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := f.Fetch(ctx, task.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка загрузки страницы: %w", err)
+	}
+
+	result := &plan.PlanResult{
+		URL:        task.URL,
+		PlanName:   p.Name(),
+		Depth:      task.Depth,
+		HTML:       string(resp.Body),
+		Data:       make(map[string]interface{}),
+		StatusCode: resp.StatusCode,
+		ParsedAt:   time.Now(),
+	}
+
+	if err := fetcher.CheckResponse(resp); err != nil {
+		return result, nil, err
+	}
+
+	doc, err := html.Parse(bytes.NewReader(resp.Body))
+	if err != nil {
+		return result, nil, utils.ParseFailure(fmt.Errorf("ошибка разбора HTML: %w", err))
+	}
+
+	if title := titleSelector.first(doc); title != nil {
+		result.Title = nodeValue(title, "")
+	}
+
+	if p.list != nil {
+		var items []map[string]interface{}
+		for _, node := range p.list.all(doc) {
+			if item, ok := p.extractFields(node, task.URL); ok {
+				items = append(items, item)
+			}
+		}
+		result.Data["items"] = items
+		result.Data["item_count"] = len(items)
+	} else if item, ok := p.extractFields(doc, task.URL); ok {
+		result.Data = item
+	}
+
+	var foundURLs []plan.FoundURL
+	if task.Depth < task.MaxDepth {
+		foundURLs = p.extractLinks(doc, task.URL)
+	}
+
+	result.Duration = time.Since(started).Milliseconds()
+	return result, foundURLs, nil
+}
+
+// extractFields возвращает false, если не найдено обязательное поле.
+func (p *DeclarativePlan) extractFields(root *html.Node, pageURL string) (map[string]interface{}, bool) {
+	item := make(map[string]interface{}, len(p.fields))
+
+	for _, f := range p.fields {
+		nodes := f.sel.all(root)
+
+		if f.Multiple {
+			var values []interface{}
+			for _, n := range nodes {
+				if v, ok := p.coerce(f.FieldSpec, nodeValue(n, f.Attr), pageURL); ok {
+					values = append(values, v)
+				}
+			}
+			if len(values) == 0 && f.Required {
+				return nil, false
+			}
+			item[f.Name] = values
+			continue
+		}
+
+		var value interface{}
+		ok := false
+		if len(nodes) > 0 {
+			value, ok = p.coerce(f.FieldSpec, nodeValue(nodes[0], f.Attr), pageURL)
+		}
+		if !ok {
+			if f.Required {
+				return nil, false
+			}
+			continue
+		}
+		item[f.Name] = value
+	}
+
+	return item, true
+}
+
+func (p *DeclarativePlan) extractLinks(doc *html.Node, pageURL string) []plan.FoundURL {
+	var found []plan.FoundURL
+	seen := make(map[string]bool)
+	now := time.Now()
+
+	for _, l := range p.links {
+		for _, n := range l.sel.all(doc) {
+			raw := nodeValue(n, l.Attr)
+			if raw == "" {
+				continue
+			}
+
+			resolved, err := p.norm.Resolve(pageURL, raw)
+			if err != nil || seen[resolved] {
+				continue
+			}
+			if l.match != nil && !l.match.MatchString(resolved) {
+				continue
+			}
+			seen[resolved] = true
+
+			found = append(found, plan.FoundURL{
+				URL:      resolved,
+				Plan:     l.Plan,
+				Priority: l.Priority,
+				Type:     l.Type,
+				Context:  l.Context,
+				FoundAt:  now,
+			})
+		}
+	}
+
+	return found
+}
+
+var validFieldTypes = map[string]bool{
+	"":       true,
+	"string": true,
+	"int":    true,
+	"float":  true,
+	"bool":   true,
+	"time":   true,
+	"url":    true,
+}
+
+var numberRe = regexp.MustCompile(`-?\d[\d,]*(\.\d+)?`)
+
+// coerce приводит строку к типу поля. false - значение пустое или не приводится.
+func (p *DeclarativePlan) coerce(f FieldSpec, raw string, pageURL string) (interface{}, bool) {
+	if raw == "" {
+		return nil, false
+	}
+
+	switch f.Type {
+	case "int":
+		num := strings.ReplaceAll(numberRe.FindString(raw), ",", "")
+		if i := strings.IndexByte(num, '.'); i >= 0 {
+			num = num[:i]
+		}
+		v, err := strconv.Atoi(num)
+		return v, err == nil
+	case "float":
+		v, err := strconv.ParseFloat(strings.ReplaceAll(numberRe.FindString(raw), ",", ""), 64)
+		return v, err == nil
+	case "bool":
+		v, err := strconv.ParseBool(raw)
+		return v, err == nil
+	case "time":
+		layout := f.Layout
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		if v, err := time.Parse(layout, raw); err == nil {
+			return v, true
+		}
+		// Запасной вариант для относительного времени вида "3 hours ago"
+		if strings.HasSuffix(raw, " ago") {
+			return parseRelativeTime(raw), true
+		}
+		return nil, false
+	case "url":
+		v, err := p.norm.Resolve(pageURL, raw)
+		return v, err == nil
+	default:
+		return raw, true
+	}
+}
+
+var titleSelector = mustSelector("title")
+
+func mustSelector(raw string) *selector {
+	s, err := compileSelector(raw)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+package plans
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go_parser/internal/domain/fetcher"
+	"go_parser/internal/domain/plan"
+	"go_parser/internal/domain/task"
+	"go_parser/internal/urlnorm"
+	"go_parser/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// GenericPlan - запасной план для URL без своего плана: сохраняет метаданные
+// страницы и не порождает новых задач, чтобы обход не расползался по внешним сайтам.
+type GenericPlan struct {
+	name     string
+	fetchers fetcher.Registry
+	norm     *urlnorm.Normalizer
+}
+
+func NewGenericPlan(fetchers fetcher.Registry, norm *urlnorm.Normalizer) *GenericPlan {
+	return &GenericPlan{
+		name:     "generic",
+		fetchers: fetchers,
+		norm:     norm,
+	}
+}
+
+func (p *GenericPlan) Name() string {
+	return p.name
+}
+
+func (p *GenericPlan) Domain() string {
+	return ""
+}
+
+// Match всегда false: generic подходит любому URL и перебил бы при подборе плана
+// все остальные. Он выбирается только как fallback или по имени.
+func (p *GenericPlan) Match(url string) bool {
+	return false
+}
+
+func (p *GenericPlan) Execute(ctx context.Context, task *task.Task) (*plan.PlanResult, []plan.FoundURL, error) {
+	started := time.Now()
+
+	f, err := p.fetchers.Select(task.Options, "http")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := f.Fetch(ctx, task.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка загрузки страницы: %w", err)
+	}
+
+	result := &plan.PlanResult{
+		URL:        task.URL,
+		PlanName:   p.Name(),
+		Depth:      task.Depth,
+		HTML:       string(resp.Body),
+		Data:       make(map[string]interface{}),
+		StatusCode: resp.StatusCode,
+		ParsedAt:   time.Now(),
+	}
+
+	if err := fetcher.CheckResponse(resp); err != nil {
+		return result, nil, err
+	}
+
+	finalURL := resp.FinalURL
+	if finalURL == "" {
+		finalURL = task.URL
+	}
+	contentType := resp.Headers.Get("Content-Type")
+	result.Data["final_url"] = finalURL
+	result.Data["content_type"] = contentType
+
+	if contentType != "" && !strings.Contains(contentType, "html") {
+		result.Duration = time.Since(started).Milliseconds()
+		return result, nil, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body))
+	if err != nil {
+		return result, nil, utils.ParseFailure(fmt.Errorf("ошибка разбора HTML: %w", err))
+	}
+
+	result.Title = strings.TrimSpace(doc.Find("title").First().Text())
+	result.Data["title"] = result.Title
+
+	if desc, ok := doc.Find(`meta[name="description"]`).Attr("content"); ok {
+		result.Data["description"] = strings.TrimSpace(desc)
+	}
+	if canonical, ok := doc.Find(`link[rel="canonical"]`).Attr("href"); ok {
+		if resolved, err := p.norm.Resolve(finalURL, canonical); err == nil {
+			result.Data["canonical"] = resolved
+		}
+	}
+
+	var headings []string
+	doc.Find("h1").Each(func(_ int, s *goquery.Selection) {
+		if text := strings.TrimSpace(s.Text()); text != "" {
+			headings = append(headings, text)
+		}
+	})
+	result.Data["headings"] = headings
+	result.Data["link_count"] = doc.Find("a[href]").Length()
+
+	result.Duration = time.Since(started).Milliseconds()
+	return result, nil, nil
+}
@@ -1,15 +1,20 @@
 package plans
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"go_parser/internal/domain/fetcher"
 	"go_parser/internal/domain/plan"
 	"go_parser/internal/domain/task"
+	"go_parser/internal/urlnorm"
 	"go_parser/internal/utils"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/playwright-community/playwright-go"
+	"github.com/PuerkitoBio/goquery"
 )
 
 type PostData struct {
@@ -31,19 +36,16 @@ type CommentData struct {
 }
 
 type HackerNewsPlan struct {
-	name string
-	pw   *playwright.Playwright
+	name     string
+	fetchers fetcher.Registry
+	norm     *urlnorm.Normalizer
 }
 
-func NewHackerNewsPlan() *HackerNewsPlan {
-	pw, err := playwright.Run()
-	if err != nil {
-		utils.Logger.Fatalf("не удалось запустить playwright: %v", err)
-	}
-
+func NewHackerNewsPlan(fetchers fetcher.Registry, norm *urlnorm.Normalizer) *HackerNewsPlan {
 	return &HackerNewsPlan{
-		name: "hackernews",
-		pw:   pw,
+		name:     "hackernews",
+		fetchers: fetchers,
+		norm:     norm,
 	}
 }
 
@@ -55,139 +57,97 @@ func (p *HackerNewsPlan) Domain() string {
 	return "news.ycombinator.com"
 }
 
-func (p *HackerNewsPlan) Match(url string) bool {
-	return strings.Contains(url, "news.ycombinator.com")
-}
-
-func (p *HackerNewsPlan) Execute(task *task.Task) (*plan.PlanResult, []plan.FoundURL, error) {
-	browser, err := p.pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
-		Headless: playwright.Bool(true),
-	})
+func (p *HackerNewsPlan) Match(rawURL string) bool {
+	u, err := url.Parse(rawURL)
 	if err != nil {
-		return nil, nil, fmt.Errorf("ошибка запуска браузера: %w", err)
+		return false
 	}
-	defer browser.Close()
+	return strings.EqualFold(u.Hostname(), p.Domain())
+}
 
-	page, err := browser.NewPage()
+func (p *HackerNewsPlan) Execute(ctx context.Context, task *task.Task) (*plan.PlanResult, []plan.FoundURL, error) {
+	started := time.Now()
+
+	// HN отдаёт статический HTML, браузер нужен только по явной опции задачи
+	f, err := p.fetchers.Select(task.Options, "http")
 	if err != nil {
-		return nil, nil, fmt.Errorf("ошибка создания страницы: %w", err)
+		return nil, nil, err
 	}
-	defer page.Close()
 
-	_, err = page.Goto(task.URL, playwright.PageGotoOptions{
-		WaitUntil: playwright.WaitUntilStateNetworkidle,
-	})
+	resp, err := f.Fetch(ctx, task.URL)
 	if err != nil {
-		return nil, nil, fmt.Errorf("ошибка навигации: %w", err)
+		return nil, nil, fmt.Errorf("ошибка загрузки страницы: %w", err)
 	}
 
-	if strings.Contains(task.URL, "item?id=") {
-		return p.parsePost(page, task)
+	result := &plan.PlanResult{
+		URL:        task.URL,
+		PlanName:   p.Name(),
+		Depth:      task.Depth,
+		HTML:       string(resp.Body),
+		Data:       make(map[string]interface{}),
+		StatusCode: resp.StatusCode,
+		ParsedAt:   time.Now(),
 	}
 
-	return p.parseFrontPage(page, task)
-}
-
-func safeTextContent(locator playwright.Locator) string {
-	text, err := locator.TextContent()
-	if err != nil {
-		return ""
+	if err := fetcher.CheckResponse(resp); err != nil {
+		return result, nil, err
 	}
-	return text
-}
 
-func safeGetAttribute(locator playwright.Locator, attr string) string {
-	val, err := locator.GetAttribute(attr)
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body))
 	if err != nil {
-		return ""
+		return result, nil, utils.ParseFailure(fmt.Errorf("ошибка разбора HTML: %w", err))
 	}
-	return val
-}
+	result.Title = strings.TrimSpace(doc.Find("title").First().Text())
 
-func safeCount(locator playwright.Locator) int {
-	count, err := locator.Count()
-	if err != nil {
-		return 0
+	var foundURLs []plan.FoundURL
+	if strings.Contains(task.URL, "item?id=") {
+		p.parsePost(doc, task, result)
+	} else {
+		foundURLs = p.parseFrontPage(doc, task, result)
 	}
-	return count
-}
 
-func safeInnerHTML(locator playwright.Locator) string {
-	html, err := locator.InnerHTML()
-	if err != nil {
-		return ""
-	}
-	return html
+	result.Duration = time.Since(started).Milliseconds()
+	return result, foundURLs, nil
 }
 
-func (p *HackerNewsPlan) parseFrontPage(page playwright.Page, task *task.Task) (*plan.PlanResult, []plan.FoundURL, error) {
-	result := &plan.PlanResult{
-		URL:      task.URL,
-		PlanName: p.Name(),
-		Depth:    task.Depth,
-		Data:     make(map[string]interface{}),
-		ParsedAt: time.Now(),
-	}
-
+func (p *HackerNewsPlan) parseFrontPage(doc *goquery.Document, task *task.Task, result *plan.PlanResult) []plan.FoundURL {
 	var foundURLs []plan.FoundURL
-
-	posts, err := page.Locator(".athing").All()
-	if err != nil {
-		utils.Logger.Printf("Ошибка поиска постов: %v", err)
-		return result, foundURLs, nil
-	}
-
 	var postsData []PostData
 
-	for _, post := range posts {
-		id := safeGetAttribute(post, "id")
+	doc.Find("tr.athing").Each(func(_ int, post *goquery.Selection) {
+		id, _ := post.Attr("id")
 
-		titleLink := post.Locator(".titleline a").First()
-		title := safeTextContent(titleLink)
-		postURL := safeGetAttribute(titleLink, "href")
+		titleLink := post.Find(".titleline > a").First()
+		title := strings.TrimSpace(titleLink.Text())
+		postURL, _ := titleLink.Attr("href")
 
-		if postURL != "" && !strings.HasPrefix(postURL, "http") {
-			postURL = "https://news.ycombinator.com/" + postURL
-		}
-
-		nextRow := post.Locator("xpath=following-sibling::tr[1]")
-		scoreText := safeTextContent(nextRow.Locator(".score"))
-		user := safeTextContent(nextRow.Locator(".hnuser"))
-		ageText := safeTextContent(nextRow.Locator(".age a"))
-		commentsLink := safeGetAttribute(nextRow.Locator(".subline a:last-child"), "href")
-
-		points := 0
-		if scoreText != "" {
-			fields := strings.Fields(scoreText)
-			if len(fields) > 0 {
-				points, _ = strconv.Atoi(fields[0])
+		if postURL != "" {
+			if resolved, err := p.norm.Resolve(task.URL, postURL); err == nil {
+				postURL = resolved
 			}
 		}
 
-		postedTime := time.Now()
-		if ageText != "" {
-			postedTime = parseRelativeTime(ageText)
-		}
-
-		comments := 0
-		if commentsLink != "" && commentsLink != "item?id=" {
-			commentsStr := strings.TrimPrefix(commentsLink, "item?id=")
-			comments, _ = strconv.Atoi(commentsStr)
-		}
+		subline := post.Next()
+		scoreText := subline.Find(".score").Text()
+		user := subline.Find(".hnuser").Text()
+		commentsText := subline.Find(".subline > a").Last().Text()
 
 		postData := PostData{
 			Title:      title,
 			URL:        postURL,
-			Points:     points,
+			Points:     leadingInt(scoreText),
 			Author:     user,
-			PostedTime: postedTime,
-			Comments:   comments,
+			PostedTime: ageTime(subline.Find(".age")),
+			Comments:   leadingInt(commentsText),
 			PostID:     id,
 		}
 		postsData = append(postsData, postData)
 
-		if comments > 0 && task.Depth < task.MaxDepth && id != "" {
-			commentURL := fmt.Sprintf("https://news.ycombinator.com/item?id=%s", id)
+		if postData.Comments > 0 && task.Depth < task.MaxDepth && id != "" {
+			commentURL, err := p.norm.Resolve(task.URL, "item?id="+id)
+			if err != nil {
+				return
+			}
 			foundURLs = append(foundURLs, plan.FoundURL{
 				URL:      commentURL,
 				Plan:     p.Name(),
@@ -199,100 +159,122 @@ func (p *HackerNewsPlan) parseFrontPage(page playwright.Page, task *task.Task) (
 				},
 			})
 		}
-	}
+	})
 
-	moreLink := safeGetAttribute(page.Locator(".morelink a"), "href")
+	moreLink, _ := doc.Find("a.morelink").First().Attr("href")
 	if moreLink != "" && task.Depth < task.MaxDepth {
-		nextURL := "https://news.ycombinator.com/" + moreLink
-		foundURLs = append(foundURLs, plan.FoundURL{
-			URL:      nextURL,
-			Plan:     p.Name(),
-			Priority: 2,
-			Type:     "pagination",
-		})
+		if nextURL, err := p.norm.Resolve(task.URL, moreLink); err == nil {
+			foundURLs = append(foundURLs, plan.FoundURL{
+				URL:      nextURL,
+				Plan:     p.Name(),
+				Priority: 2,
+				Type:     "pagination",
+			})
+		}
 	}
 
 	result.Data["posts"] = postsData
 	result.Data["post_count"] = len(postsData)
 
-	return result, foundURLs, nil
+	return foundURLs
 }
 
-func (p *HackerNewsPlan) parsePost(page playwright.Page, task *task.Task) (*plan.PlanResult, []plan.FoundURL, error) {
-	result := &plan.PlanResult{
-		URL:      task.URL,
-		PlanName: p.Name(),
-		Depth:    task.Depth,
-		Data:     make(map[string]interface{}),
-		ParsedAt: time.Now(),
+func (p *HackerNewsPlan) parsePost(doc *goquery.Document, task *task.Task, result *plan.PlanResult) {
+	header := doc.Find(".fatitem tr.athing").First()
+	titleLink := header.Find(".titleline > a").First()
+	postURL, _ := titleLink.Attr("href")
+	if postURL != "" {
+		if resolved, err := p.norm.Resolve(task.URL, postURL); err == nil {
+			postURL = resolved
+		}
 	}
+	id, _ := header.Attr("id")
 
-	titleLocator := page.Locator(".title a").First()
-	title := safeTextContent(titleLocator)
-	postURL := safeGetAttribute(titleLocator, "href")
-
+	subline := doc.Find(".fatitem .subline").First()
 	postData := PostData{
-		Title: title,
-		URL:   postURL,
+		Title:      strings.TrimSpace(titleLink.Text()),
+		URL:        postURL,
+		Points:     leadingInt(subline.Find(".score").Text()),
+		Author:     subline.Find(".hnuser").Text(),
+		PostedTime: ageTime(subline.Find(".age")),
+		Comments:   leadingInt(subline.Find("a").Last().Text()),
+		PostID:     id,
 	}
 
-	scoreText := safeTextContent(page.Locator(".score"))
-	if scoreText != "" {
-		fields := strings.Fields(scoreText)
-		if len(fields) > 0 {
-			postData.Points, _ = strconv.Atoi(fields[0])
-		}
-	}
-
-	user := safeTextContent(page.Locator(".hnuser").First())
-	postData.Author = user
-
-	comments := p.parseComments(page, 0, task.MaxDepth)
+	comments := p.parseComments(doc, id, task.MaxDepth)
 	result.Data["post"] = postData
 	result.Data["comments"] = comments
 	result.Data["comments_count"] = len(comments)
-
-	return result, nil, nil
 }
 
-func (p *HackerNewsPlan) parseComments(page playwright.Page, level int, maxDepth int) []CommentData {
-	if level >= maxDepth {
-		return nil
-	}
-
+// parseComments разбирает плоский список tr.comtr; вложенность задаёт атрибут indent.
+func (p *HackerNewsPlan) parseComments(doc *goquery.Document, postID string, maxDepth int) []CommentData {
 	var comments []CommentData
+	// parents[level] - ID последнего комментария на этом уровне
+	var parents []string
 
-	commentRows, err := page.Locator(".comment").All()
-	if err != nil {
-		utils.Logger.Printf("Ошибка поиска комментариев: %v", err)
-		return nil
-	}
+	doc.Find("tr.comtr").Each(func(_ int, row *goquery.Selection) {
+		indent, _ := row.Find("td.ind").Attr("indent")
+		level, _ := strconv.Atoi(indent)
+		id, _ := row.Attr("id")
 
-	for _, row := range commentRows {
-		author := safeTextContent(row.Locator(".hnuser"))
+		parentID := postID
+		if level > 0 && level <= len(parents) {
+			parentID = parents[level-1]
+		}
+		if level < len(parents) {
+			parents = parents[:level]
+		}
+		parents = append(parents, id)
 
-		text := safeInnerHTML(row.Locator(".comment").First())
+		if level >= maxDepth {
+			return
+		}
 
-		age := safeTextContent(row.Locator(".age a"))
+		text, _ := row.Find(".commtext").First().Html()
 
-		comment := CommentData{
-			Author: author,
-			Text:   text,
-			Time:   parseRelativeTime(age),
-			Level:  level,
-		}
+		comments = append(comments, CommentData{
+			Author:   row.Find(".hnuser").First().Text(),
+			Text:     text,
+			Time:     ageTime(row.Find(".age")),
+			ParentID: parentID,
+			Level:    level,
+		})
+	})
 
-		comments = append(comments, comment)
+	return comments
+}
 
-		// Проверяем наличие ответов
-		replies := row.Locator("xpath=following-sibling::tr[contains(@class, 'comment')]")
-		if safeCount(replies) > 0 {
-			nestedComments := p.parseComments(page, level+1, maxDepth)
-			comments = append(comments, nestedComments...)
+// ageTime берёт абсолютное время из атрибута title элемента .age: "2025-03-01T09:30:00 1740821400"
+// (ISO-время в UTC и unix-время) или, на старых страницах, только ISO. Текст вида "3 hours ago"
+// не используется: вычисленное от time.Now время менялось бы при каждом обходе вместе с
+// хешем данных. Без title время остаётся нулевым.
+func ageTime(age *goquery.Selection) time.Time {
+	title, _ := age.First().Attr("title")
+	fields := strings.Fields(title)
+	if len(fields) == 0 {
+		return time.Time{}
+	}
+	if len(fields) > 1 {
+		if sec, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			return time.Unix(sec, 0).UTC()
 		}
 	}
+	t, err := time.Parse("2006-01-02T15:04:05", fields[0])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
 
-	return comments
+// leadingInt извлекает число из строк вида "123 points" или "45&nbsp;comments".
+func leadingInt(s string) int {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0
+	}
+	n, _ := strconv.Atoi(fields[0])
+	return n
 }
 
 func parseRelativeTime(age string) time.Time {
@@ -0,0 +1,227 @@
+package plans
+
+import (
+	"context"
+	"go_parser/internal/database"
+	"go_parser/internal/domain/fetcher"
+	"go_parser/internal/domain/record"
+	"go_parser/internal/domain/task"
+	"go_parser/internal/records"
+	"go_parser/internal/services"
+	"go_parser/internal/urlnorm"
+	"go_parser/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const hnFrontPage = `<html><head><title>Hacker News</title></head><body><table>
+<tr class="athing" id="101">
+  <td><span class="titleline"><a href="https://example.com/a">First post</a></span></td>
+</tr>
+<tr><td class="subtext"><span class="subline">
+  <span class="score">120 points</span> by <a class="hnuser">alice</a>
+  <span class="age" title="2025-03-01T09:30:00 1740821400"><a href="item?id=101">3 hours ago</a></span> |
+  <a href="item?id=101">45&nbsp;comments</a>
+</span></td></tr>
+<tr class="athing" id="102">
+  <td><span class="titleline"><a href="item?id=102">Ask HN: second</a></span></td>
+</tr>
+<tr><td class="subtext"><span class="subline">
+  <span class="score">7 points</span> by <a class="hnuser">bob</a>
+  <span class="age" title="2025-03-01T12:20:00"><a href="item?id=102">10 minutes ago</a></span> |
+  <a href="item?id=102">discuss</a>
+</span></td></tr>
+</table><a class="morelink" href="news?p=2">More</a></body></html>`
+
+const hnItemPage = `<html><head><title>First post | Hacker News</title></head><body>
+<table class="fatitem">
+<tr class="athing" id="101">
+  <td><span class="titleline"><a href="https://example.com/a">First post</a></span></td>
+</tr>
+<tr><td class="subtext"><span class="subline">
+  <span class="score">120 points</span> by <a class="hnuser">alice</a>
+  <span class="age" title="2025-03-01T09:30:00 1740821400"><a href="item?id=101">3 hours ago</a></span> |
+  <a href="item?id=101">2&nbsp;comments</a>
+</span></td></tr>
+</table>
+<table class="comment-tree">
+<tr class="athing comtr" id="201"><td class="ind" indent="0"></td><td>
+  <a class="hnuser">carol</a> <span class="age" title="2025-03-01T11:30:00 1740828600"><a>1 hour ago</a></span>
+  <div class="commtext">top level</div>
+</td></tr>
+<tr class="athing comtr" id="202"><td class="ind" indent="1"></td><td>
+  <a class="hnuser">dave</a> <span class="age"><a>5 minutes ago</a></span>
+  <div class="commtext">reply</div>
+</td></tr>
+</table></body></html>`
+
+func newHNTestPlan(t *testing.T) (*HackerNewsPlan, string) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/news", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(hnFrontPage))
+	})
+	mux.HandleFunc("/item", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(hnItemPage))
+	})
+	mux.HandleFunc("/limited", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	fetchers := fetcher.NewRegistry(services.NewHTTPFetcher(5*time.Second, "test-agent"))
+	return NewHackerNewsPlan(fetchers, urlnorm.New(nil)), srv.URL
+}
+
+func TestHackerNewsFrontPage(t *testing.T) {
+	p, base := newHNTestPlan(t)
+
+	res, found, err := p.Execute(context.Background(), &task.Task{URL: base + "/news", Depth: 0, MaxDepth: 2})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if res.Title != "Hacker News" {
+		t.Errorf("Title = %q", res.Title)
+	}
+
+	posts, ok := res.Data["posts"].([]PostData)
+	if !ok || len(posts) != 2 {
+		t.Fatalf("posts = %#v", res.Data["posts"])
+	}
+	first := posts[0]
+	if first.PostID != "101" || first.Title != "First post" || first.URL != "https://example.com/a" {
+		t.Errorf("первый пост = %+v", first)
+	}
+	if first.Points != 120 || first.Author != "alice" || first.Comments != 45 {
+		t.Errorf("счётчики первого поста = %+v", first)
+	}
+	if want := time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC); !first.PostedTime.Equal(want) {
+		t.Errorf("PostedTime = %s, want %s", first.PostedTime, want)
+	}
+	if posts[1].URL != base+"/item?id=102" || posts[1].Comments != 0 {
+		t.Errorf("второй пост = %+v", posts[1])
+	}
+	// title без unix-времени, как на старых страницах
+	if want := time.Date(2025, 3, 1, 12, 20, 0, 0, time.UTC); !posts[1].PostedTime.Equal(want) {
+		t.Errorf("PostedTime второго поста = %s, want %s", posts[1].PostedTime, want)
+	}
+
+	// Комментарии только у поста с ненулевым счётчиком, затем следующая страница
+	want := []struct{ url, typ string }{
+		{base + "/item?id=101", "comments"},
+		{base + "/news?p=2", "pagination"},
+	}
+	if len(found) != len(want) {
+		t.Fatalf("найдено ссылок %d, want %d: %+v", len(found), len(want), found)
+	}
+	for i, w := range want {
+		if found[i].URL != w.url || found[i].Type != w.typ || found[i].Plan != "hackernews" {
+			t.Errorf("ссылка %d = %+v, want %s (%s)", i, found[i], w.url, w.typ)
+		}
+	}
+}
+
+func TestHackerNewsMaxDepth(t *testing.T) {
+	p, base := newHNTestPlan(t)
+
+	_, found, err := p.Execute(context.Background(), &task.Task{URL: base + "/news", Depth: 1, MaxDepth: 1})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if len(found) != 0 {
+		t.Errorf("на последнем уровне найдены ссылки: %+v", found)
+	}
+}
+
+func TestHackerNewsItem(t *testing.T) {
+	p, base := newHNTestPlan(t)
+
+	res, found, err := p.Execute(context.Background(), &task.Task{URL: base + "/item?id=101", Depth: 1, MaxDepth: 3})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if len(found) != 0 {
+		t.Errorf("страница поста не должна порождать ссылки: %+v", found)
+	}
+
+	post, ok := res.Data["post"].(PostData)
+	if !ok || post.PostID != "101" || post.Comments != 2 || post.Author != "alice" {
+		t.Errorf("post = %#v", res.Data["post"])
+	}
+
+	comments, ok := res.Data["comments"].([]CommentData)
+	if !ok || len(comments) != 2 {
+		t.Fatalf("comments = %#v", res.Data["comments"])
+	}
+	if comments[0].ParentID != "101" || comments[0].Level != 0 || comments[0].Author != "carol" {
+		t.Errorf("первый комментарий = %+v", comments[0])
+	}
+	if comments[1].ParentID != "201" || comments[1].Level != 1 || comments[1].Text != "reply" {
+		t.Errorf("ответ = %+v", comments[1])
+	}
+	if want := time.Date(2025, 3, 1, 11, 30, 0, 0, time.UTC); !comments[0].Time.Equal(want) {
+		t.Errorf("время комментария = %s, want %s", comments[0].Time, want)
+	}
+	// Без title время не вычисляется от текущего момента
+	if !comments[1].Time.IsZero() {
+		t.Errorf("время ответа без title = %s, want нулевое", comments[1].Time)
+	}
+}
+
+func TestHackerNewsRecrawlUnchanged(t *testing.T) {
+	p, base := newHNTestPlan(t)
+	store := records.NewStore(
+		database.NewMemoryRepository[*record.Record](),
+		database.NewMemoryRepository[*record.History](),
+		nil,
+	)
+
+	for _, path := range []string{"/news", "/item?id=101"} {
+		t.Run(path, func(t *testing.T) {
+			var outcomes []string
+			for range 2 {
+				res, _, err := p.Execute(context.Background(), &task.Task{URL: base + path, MaxDepth: 2})
+				if err != nil {
+					t.Fatalf("Execute: %v", err)
+				}
+				saved, err := store.Save(context.Background(), &record.Record{
+					URL:      res.URL,
+					PlanName: res.PlanName,
+					Data:     res.Data,
+					ParsedAt: res.ParsedAt,
+				})
+				if err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+				outcomes = append(outcomes, saved.Outcome)
+			}
+
+			if outcomes[0] != records.OutcomeCreated || outcomes[1] != records.OutcomeUnchanged {
+				t.Errorf("итоги сохранения = %v, want [%s %s]", outcomes, records.OutcomeCreated, records.OutcomeUnchanged)
+			}
+		})
+	}
+}
+
+func TestHackerNewsTooManyRequests(t *testing.T) {
+	p, base := newHNTestPlan(t)
+
+	res, _, err := p.Execute(context.Background(), &task.Task{URL: base + "/limited"})
+	if err == nil {
+		t.Fatal("ожидалась ошибка")
+	}
+	if res == nil || res.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("результат = %+v", res)
+	}
+	if got := utils.CategoryOf(err); got != utils.CategoryTransient {
+		t.Errorf("категория = %s, want transient", got)
+	}
+	if got := utils.RetryAfterOf(err); got != 30*time.Second {
+		t.Errorf("RetryAfter = %s, want 30s", got)
+	}
+}
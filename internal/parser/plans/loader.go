@@ -0,0 +1,78 @@
+package plans
+
+import (
+	"encoding/json"
+	"fmt"
+	"go_parser/internal/domain/fetcher"
+	"go_parser/internal/urlnorm"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadDeclarativePlans читает все *.yaml, *.yml и *.json из dir в алфавитном порядке.
+// Отсутствующий каталог не считается ошибкой.
+func LoadDeclarativePlans(dir string, fetchers fetcher.Registry, norm *urlnorm.Normalizer) ([]*DeclarativePlan, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения каталога планов %s: %w", dir, err)
+	}
+
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".yaml", ".yml", ".json":
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+
+	var result []*DeclarativePlan
+	for _, file := range files {
+		spec, err := readPlanSpec(file)
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := NewDeclarativePlan(spec, fetchers, norm)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		result = append(result, p)
+	}
+
+	return result, nil
+}
+
+func readPlanSpec(file string) (PlanSpec, error) {
+	var spec PlanSpec
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return spec, fmt.Errorf("ошибка чтения плана %s: %w", file, err)
+	}
+
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		dec := json.NewDecoder(strings.NewReader(string(data)))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&spec)
+	} else {
+		dec := yaml.NewDecoder(strings.NewReader(string(data)))
+		dec.KnownFields(true)
+		err = dec.Decode(&spec)
+	}
+	if err != nil {
+		return spec, fmt.Errorf("ошибка разбора плана %s: %w", file, err)
+	}
+
+	return spec, nil
+}
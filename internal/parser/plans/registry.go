@@ -3,12 +3,17 @@ package plans
 import (
 	"fmt"
 	"go_parser/internal/domain/plan"
+	"go_parser/internal/utils"
+	"net/url"
+	"strings"
 	"sync"
 )
 
 type PlanRegistr struct {
-	mu    sync.RWMutex
-	plans map[string]plan.Plan
+	mu       sync.RWMutex
+	plans    map[string]plan.Plan
+	order    []string
+	fallback string
 }
 
 func NewRegistr() *PlanRegistr {
@@ -27,6 +32,7 @@ func (r *PlanRegistr) Register(plan plan.Plan) error {
 	}
 
 	r.plans[name] = plan
+	r.order = append(r.order, name)
 	return nil
 }
 
@@ -36,7 +42,7 @@ func (r *PlanRegistr) Get(name string) (plan.Plan, error) {
 
 	plan, exists := r.plans[name]
 	if !exists {
-		return nil, fmt.Errorf("plan %s not found", name)
+		return nil, utils.PlanNotFound(fmt.Errorf("plan %s not found", name))
 	}
 	return plan, nil
 }
@@ -45,9 +51,80 @@ func (r *PlanRegistr) List() []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	names := make([]string, 0, len(r.plans))
-	for name := range r.plans {
-		names = append(names, name)
-	}
+	names := make([]string, len(r.order))
+	copy(names, r.order)
 	return names
 }
+
+// SetFallback задаёт план, который получает URL без подходящего плана.
+func (r *PlanRegistr) SetFallback(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.plans[name]; !exists {
+		return fmt.Errorf("plan %s not found", name)
+	}
+	r.fallback = name
+	return nil
+}
+
+// Match ищет план по URL без учёта fallback. Среди планов, для которых Match(url) = true,
+// выигрывает точное совпадение хоста с Domain(), затем поддомен, затем остальные;
+// при равенстве - план, зарегистрированный раньше.
+func (r *PlanRegistr) Match(rawURL string) (plan.Plan, bool) {
+	host := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var best plan.Plan
+	bestScore := -1
+	for _, name := range r.order {
+		if name == r.fallback {
+			continue
+		}
+
+		p := r.plans[name]
+		if !p.Match(rawURL) {
+			continue
+		}
+
+		if score := domainScore(host, p.Domain()); score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+
+	return best, best != nil
+}
+
+// Resolve возвращает план по URL, а если подходящего нет - fallback-план.
+func (r *PlanRegistr) Resolve(rawURL string) (plan.Plan, error) {
+	if p, ok := r.Match(rawURL); ok {
+		return p, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.fallback == "" {
+		return nil, utils.PlanNotFound(fmt.Errorf("plan for %s not found", rawURL))
+	}
+	return r.plans[r.fallback], nil
+}
+
+func domainScore(host, domain string) int {
+	domain = strings.ToLower(domain)
+	switch {
+	case domain == "":
+		return 0
+	case host == domain:
+		return 2
+	case strings.HasSuffix(host, "."+domain):
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,76 @@
+package plans
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+)
+
+const xpathPrefix = "xpath="
+
+// selector - CSS-селектор или XPath-выражение с префиксом "xpath=", как в локаторах playwright.
+type selector struct {
+	raw   string
+	css   cascadia.Selector
+	xpath *xpath.Expr
+}
+
+func compileSelector(raw string) (*selector, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	s := &selector{raw: raw}
+
+	if expr, ok := strings.CutPrefix(raw, xpathPrefix); ok {
+		compiled, err := xpath.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("невалидный xpath %q: %w", expr, err)
+		}
+		s.xpath = compiled
+		return s, nil
+	}
+
+	compiled, err := cascadia.Compile(raw)
+	if err != nil {
+		return nil, fmt.Errorf("невалидный css-селектор %q: %w", raw, err)
+	}
+	s.css = compiled
+	return s, nil
+}
+
+// all ищет совпадения среди потомков root. Пустой селектор означает сам root.
+func (s *selector) all(root *html.Node) []*html.Node {
+	if s == nil {
+		return []*html.Node{root}
+	}
+	if s.xpath != nil {
+		return htmlquery.QuerySelectorAll(root, s.xpath)
+	}
+	return cascadia.QueryAll(root, s.css)
+}
+
+func (s *selector) first(root *html.Node) *html.Node {
+	nodes := s.all(root)
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[0]
+}
+
+// nodeValue возвращает атрибут узла, его HTML (attr = "html") или текст.
+func nodeValue(n *html.Node, attr string) string {
+	switch attr {
+	case "":
+		return strings.TrimSpace(htmlquery.InnerText(n))
+	case "html":
+		return htmlquery.OutputHTML(n, false)
+	default:
+		return strings.TrimSpace(htmlquery.SelectAttr(n, attr))
+	}
+}
@@ -0,0 +1,92 @@
+package politeness
+
+import (
+	"context"
+	"fmt"
+	"go_parser/internal/domain/fetcher"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+type robotsEntry struct {
+	robots    *Robots
+	fetchedAt time.Time
+}
+
+// RobotsCache загружает robots.txt один раз на хост и держит его ttl.
+type RobotsCache struct {
+	fetcher fetcher.Fetcher
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*robotsEntry
+	// loading не даёт воркерам одновременно качать robots.txt одного хоста
+	loading map[string]*sync.Mutex
+}
+
+func NewRobotsCache(f fetcher.Fetcher, ttl time.Duration) *RobotsCache {
+	return &RobotsCache{
+		fetcher: f,
+		ttl:     ttl,
+		entries: make(map[string]*robotsEntry),
+		loading: make(map[string]*sync.Mutex),
+	}
+}
+
+// Get возвращает robots.txt для scheme://host страницы u.
+func (c *RobotsCache) Get(ctx context.Context, u *url.URL) (*Robots, error) {
+	origin := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	if e, ok := c.entries[origin]; ok && time.Since(e.fetchedAt) < c.ttl {
+		c.mu.Unlock()
+		return e.robots, nil
+	}
+	lock, ok := c.loading[origin]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.loading[origin] = lock
+	}
+	c.mu.Unlock()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Пока ждали, robots.txt мог загрузить другой воркер
+	c.mu.Lock()
+	if e, ok := c.entries[origin]; ok && time.Since(e.fetchedAt) < c.ttl {
+		c.mu.Unlock()
+		return e.robots, nil
+	}
+	c.mu.Unlock()
+
+	robots, err := c.fetch(ctx, origin)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[origin] = &robotsEntry{robots: robots, fetchedAt: time.Now()}
+	c.mu.Unlock()
+
+	return robots, nil
+}
+
+// fetch: 4xx означает отсутствие ограничений, 5xx и сетевые ошибки - временную ошибку.
+func (c *RobotsCache) fetch(ctx context.Context, origin string) (*Robots, error) {
+	resp, err := c.fetcher.Fetch(ctx, origin+"/robots.txt")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки robots.txt %s: %w", origin, err)
+	}
+
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return nil, fmt.Errorf("robots.txt %s недоступен: статус %d", origin, resp.StatusCode)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return allowAll, nil
+	default:
+		return ParseRobots(resp.Body), nil
+	}
+}
@@ -0,0 +1,62 @@
+package politeness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_parser/internal/utils"
+	"net/url"
+	"time"
+)
+
+// ErrDisallowed - URL запрещён robots.txt; такую задачу не нужно повторять.
+var ErrDisallowed = errors.New("запрещено robots.txt")
+
+// maxCrawlDelay ограничивает Crawl-delay из robots.txt, чтобы один хост не занимал воркер надолго.
+const maxCrawlDelay = time.Minute
+
+// Gate проверяет robots.txt и выдерживает ограничения хоста перед запросом страницы.
+type Gate struct {
+	robots      *RobotsCache
+	limiter     Limiter
+	userAgent   string
+	minInterval time.Duration
+}
+
+func NewGate(robots *RobotsCache, limiter Limiter, userAgent string, minInterval time.Duration) *Gate {
+	return &Gate{
+		robots:      robots,
+		limiter:     limiter,
+		userAgent:   userAgent,
+		minInterval: minInterval,
+	}
+}
+
+// Acquire блокируется, пока к хосту rawURL можно обращаться. release вызывается после запроса.
+func (g *Gate) Acquire(ctx context.Context, rawURL string) (release func(), err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, utils.InvalidTask(fmt.Errorf("невалидный URL %s: %w", rawURL, err))
+	}
+
+	robots, err := g.robots.Get(ctx, u)
+	if err != nil {
+		return nil, utils.Transient(err)
+	}
+
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if !robots.Allowed(g.userAgent, path) {
+		return nil, utils.InvalidTask(fmt.Errorf("%s: %w", rawURL, ErrDisallowed))
+	}
+
+	interval := max(g.minInterval, min(robots.CrawlDelay(g.userAgent), maxCrawlDelay))
+	release, err = g.limiter.Acquire(ctx, u.Host, interval)
+	if err != nil {
+		// Общий лимитер хранит слоты в MongoDB
+		return nil, utils.StorageFailure(err)
+	}
+	return release, nil
+}
@@ -0,0 +1,236 @@
+package politeness
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"go_parser/internal/database"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Limiter ограничивает число одновременных запросов к хосту и паузу между ними.
+// release нужно вызвать после окончания запроса.
+type Limiter interface {
+	Acquire(ctx context.Context, host string, interval time.Duration) (release func(), err error)
+}
+
+type hostState struct {
+	slots chan struct{}
+	mu    sync.Mutex
+	next  time.Time
+}
+
+// LocalLimiter - ограничения в пределах одного процесса.
+type LocalLimiter struct {
+	maxConcurrent int
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+func NewLocalLimiter(maxConcurrent int) *LocalLimiter {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	return &LocalLimiter{
+		maxConcurrent: maxConcurrent,
+		hosts:         make(map[string]*hostState),
+	}
+}
+
+func (l *LocalLimiter) host(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	st, ok := l.hosts[host]
+	if !ok {
+		st = &hostState{slots: make(chan struct{}, l.maxConcurrent)}
+		l.hosts[host] = st
+	}
+	return st
+}
+
+func (l *LocalLimiter) Acquire(ctx context.Context, host string, interval time.Duration) (func(), error) {
+	st := l.host(host)
+
+	select {
+	case st.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-st.slots }
+
+	// Резервируем момент старта: каждый следующий запрос сдвигается на interval
+	st.mu.Lock()
+	now := time.Now()
+	start := now
+	if st.next.After(now) {
+		start = st.next
+	}
+	st.next = start.Add(interval)
+	st.mu.Unlock()
+
+	if err := sleep(ctx, start.Sub(now)); err != nil {
+		release()
+		return nil, err
+	}
+
+	return release, nil
+}
+
+// Lease - аренда в общей коллекции: слот параллельности или момент следующего запроса к хосту.
+type Lease struct {
+	database.BaseEntity `bson:",inline"`
+
+	Until time.Time `bson:"until" json:"until"`
+	Owner string    `bson:"owner" json:"owner"`
+}
+
+// LeaseIndexes - аренды хостов, которые давно не запрашивались, удаляются по TTL.
+// Истёкшая аренда никому не принадлежит, поэтому удалить её безопасно.
+var LeaseIndexes = []database.Index{
+	{Keys: []database.IndexKey{database.Asc("until")}, TTL: time.Hour},
+}
+
+// MongoLimiter - ограничения, общие для всех инстансов парсера.
+// Слоты хоста - документы slot:<host>:<i>, занятые до Until; пауза - документ next:<host>.
+// Аренда берётся compare-and-set'ом по Until, поэтому упавший инстанс держит слот не дольше leaseTTL.
+type MongoLimiter struct {
+	repo          database.Repository[*Lease]
+	maxConcurrent int
+	leaseTTL      time.Duration
+	poll          time.Duration
+	owner         string
+}
+
+func NewMongoLimiter(repo database.Repository[*Lease], maxConcurrent int, leaseTTL time.Duration) *MongoLimiter {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	return &MongoLimiter{
+		repo:          repo,
+		maxConcurrent: maxConcurrent,
+		leaseTTL:      leaseTTL,
+		poll:          200 * time.Millisecond,
+		owner:         newOwnerID(),
+	}
+}
+
+func (l *MongoLimiter) Acquire(ctx context.Context, host string, interval time.Duration) (func(), error) {
+	slotID, until, err := l.acquireSlot(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	release := func() {
+		// Освобождаем только свою аренду: если она истекла, слот мог занять другой инстанс
+		filter := database.Filter{"_id": slotID, "owner": l.owner, "until": until}
+		if _, err := l.repo.UpdateOne(context.Background(), filter, map[string]interface{}{"until": time.Time{}}); err != nil {
+			log.Printf("[ERROR] не удалось освободить слот %s: %v", slotID, err)
+		}
+	}
+
+	if interval > 0 {
+		if err := l.waitInterval(ctx, host, interval); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	return release, nil
+}
+
+func (l *MongoLimiter) acquireSlot(ctx context.Context, host string) (string, time.Time, error) {
+	for {
+		until := now().Add(l.leaseTTL)
+		for i := 0; i < l.maxConcurrent; i++ {
+			id := fmt.Sprintf("slot:%s:%d", host, i)
+			ok, err := l.tryLease(ctx, id, until)
+			if err != nil {
+				return "", time.Time{}, err
+			}
+			if ok {
+				return id, until, nil
+			}
+		}
+
+		if err := sleep(ctx, l.poll); err != nil {
+			return "", time.Time{}, err
+		}
+	}
+}
+
+func (l *MongoLimiter) waitInterval(ctx context.Context, host string, interval time.Duration) error {
+	id := "next:" + host
+	for {
+		ok, err := l.tryLease(ctx, id, now().Add(interval))
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+
+		// Ждём до момента, занятого другим запросом
+		wait := l.poll
+		if lease, err := l.repo.Get(ctx, id); err == nil && lease != nil {
+			wait = min(max(time.Until(lease.Until), time.Millisecond), interval)
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// tryLease занимает документ id до until, если его аренда истекла или документа ещё нет.
+func (l *MongoLimiter) tryLease(ctx context.Context, id string, until time.Time) (bool, error) {
+	filter := database.Filter{
+		"_id":   id,
+		"until": map[string]interface{}{"lte": now()},
+	}
+	ok, err := l.repo.UpdateOne(ctx, filter, map[string]interface{}{"until": until, "owner": l.owner})
+	if err != nil || ok {
+		return ok, err
+	}
+
+	lease := &Lease{Until: until, Owner: l.owner}
+	lease.SetID(id)
+	if err := l.repo.Create(ctx, lease); err != nil {
+		if database.IsDuplicate(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// now обрезано до миллисекунд - точности дат в MongoDB, иначе сравнение Until на равенство не сработает.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+func newOwnerID() string {
+	host, _ := os.Hostname()
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(buf))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
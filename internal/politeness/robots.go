@@ -0,0 +1,164 @@
+package politeness
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// Robots - разобранный robots.txt (RFC 9309 и нестандартный Crawl-delay).
+type Robots struct {
+	groups []*robotsGroup
+}
+
+// allowAll - robots.txt отсутствует или пуст.
+var allowAll = &Robots{}
+
+func ParseRobots(data []byte) *Robots {
+	r := &Robots{}
+
+	var current *robotsGroup
+	inRules := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// Подряд идущие User-agent относятся к одной группе
+			if current == nil || inRules {
+				current = &robotsGroup{}
+				r.groups = append(r.groups, current)
+				inRules = false
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			if value == "" {
+				// Пустой Disallow разрешает всё
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{
+				allow:   key == "allow",
+				pattern: value,
+				re:      compileRobotsPattern(value),
+			})
+		case "crawl-delay":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	return r
+}
+
+// compileRobotsPattern переводит шаблон с "*" и "$" в регулярное выражение по префиксу пути.
+func compileRobotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// groupsFor возвращает группы с самым длинным совпавшим токеном агента, иначе группы "*".
+func (r *Robots) groupsFor(userAgent string) []*robotsGroup {
+	userAgent = strings.ToLower(userAgent)
+
+	var best []*robotsGroup
+	bestLen := 0
+	var wildcard []*robotsGroup
+
+	for _, g := range r.groups {
+		for _, agent := range g.agents {
+			switch {
+			case agent == "*":
+				wildcard = append(wildcard, g)
+			case agent != "" && strings.Contains(userAgent, agent):
+				if len(agent) > bestLen {
+					best, bestLen = nil, len(agent)
+				}
+				if len(agent) == bestLen {
+					best = append(best, g)
+				}
+			}
+		}
+	}
+
+	if best != nil {
+		return best
+	}
+	return wildcard
+}
+
+// Allowed проверяет путь (с query) по правилу с самым длинным шаблоном; при равной длине побеждает Allow.
+func (r *Robots) Allowed(userAgent, path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+
+	allowed := true
+	matchedLen := -1
+
+	for _, g := range r.groupsFor(userAgent) {
+		for _, rule := range g.rules {
+			if !rule.re.MatchString(path) {
+				continue
+			}
+			l := len(rule.pattern)
+			if l > matchedLen || (l == matchedLen && rule.allow) {
+				allowed, matchedLen = rule.allow, l
+			}
+		}
+	}
+
+	return allowed
+}
+
+func (r *Robots) CrawlDelay(userAgent string) time.Duration {
+	var delay time.Duration
+	for _, g := range r.groupsFor(userAgent) {
+		delay = max(delay, g.crawlDelay)
+	}
+	return delay
+}
@@ -0,0 +1,217 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"go_parser/internal/domain/queue"
+	"go_parser/internal/utils"
+	"sync"
+	"time"
+)
+
+const memoryService = "MemoryQueue"
+
+var ErrBrokerClosed = errors.New("очередь закрыта")
+
+// MemoryBroker - очередь в памяти процесса на каналах. Подходит для локального
+// запуска и тестов без RabbitMQ; сообщения не переживают перезапуск.
+type MemoryBroker struct {
+	bufferSize int
+	done       chan struct{}
+	closeOnce  sync.Once
+	// stop закрывается в StopConsuming
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	mu     sync.RWMutex
+	closed bool
+	queues map[string]chan queue.WrapperMessage
+	timers map[*time.Timer]struct{}
+}
+
+func NewMemoryBroker(bufferSize int) *MemoryBroker {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	return &MemoryBroker{
+		bufferSize: bufferSize,
+		done:       make(chan struct{}),
+		stop:       make(chan struct{}),
+		queues:     make(map[string]chan queue.WrapperMessage),
+		timers:     make(map[*time.Timer]struct{}),
+	}
+}
+
+// channel возвращает канал очереди, создавая его при первом обращении. Вызывать под mu.
+func (b *MemoryBroker) channel(name string) chan queue.WrapperMessage {
+	ch, ok := b.queues[name]
+	if !ok {
+		ch = make(chan queue.WrapperMessage, b.bufferSize)
+		b.queues[name] = ch
+	}
+	return ch
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, queueName string, msg queue.Publishing) error {
+	if msg.Exchange != "" {
+		return nil
+	}
+
+	m := &memoryMessage{
+		broker:  b,
+		queue:   queueName,
+		body:    msg.Body,
+		headers: msg.Headers,
+	}
+
+	if msg.Delay <= 0 {
+		return b.deliver(ctx, m)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return utils.NewError(memoryService, ErrBrokerClosed)
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(msg.Delay, func() {
+		b.mu.Lock()
+		delete(b.timers, timer)
+		b.mu.Unlock()
+
+		b.deliver(context.Background(), m)
+	})
+	b.timers[timer] = struct{}{}
+
+	return nil
+}
+
+// deliver кладёт сообщение в канал очереди; блокируется, пока в буфере нет места.
+func (b *MemoryBroker) deliver(ctx context.Context, m *memoryMessage) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return utils.NewError(memoryService, ErrBrokerClosed)
+	}
+	ch := b.channel(m.queue)
+	b.mu.Unlock()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return utils.NewError(memoryService, ErrBrokerClosed)
+	}
+
+	select {
+	case ch <- m:
+		return nil
+	case <-b.done:
+		return utils.NewError(memoryService, ErrBrokerClosed)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Consume отдаёт сообщения очереди через отдельный канал, чтобы StopConsuming
+// мог закрыть его, не трогая канал, в который пишут издатели.
+func (b *MemoryBroker) Consume(queueName string) (<-chan queue.WrapperMessage, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, utils.NewError(memoryService, ErrBrokerClosed)
+	}
+	select {
+	case <-b.stop:
+		return nil, utils.NewError(memoryService, ErrBrokerClosed)
+	default:
+	}
+
+	in := b.channel(queueName)
+	out := make(chan queue.WrapperMessage)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case m, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- m:
+				case <-b.stop:
+					m.TryAgain()
+					return
+				}
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (b *MemoryBroker) StopConsuming() {
+	b.stopOnce.Do(func() {
+		close(b.stop)
+	})
+}
+
+func (b *MemoryBroker) State() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return StateClosed
+	}
+	return StateConnected
+}
+
+// Close останавливает отложенные сообщения и закрывает каналы всех очередей.
+func (b *MemoryBroker) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.done)
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		b.closed = true
+		for timer := range b.timers {
+			timer.Stop()
+		}
+		for _, ch := range b.queues {
+			close(ch)
+		}
+	})
+	return nil
+}
+
+type memoryMessage struct {
+	broker  *MemoryBroker
+	queue   string
+	body    []byte
+	headers map[string]interface{}
+}
+
+func (m *memoryMessage) Success() {}
+
+func (m *memoryMessage) TryAgain() {
+	// Как nack с requeue: сообщение возвращается в конец очереди
+	go m.broker.deliver(context.Background(), m)
+}
+
+func (m *memoryMessage) Reject() {}
+
+func (m *memoryMessage) Requeue(delay time.Duration) error {
+	return m.broker.Publish(context.Background(), m.queue, queue.Publishing{
+		Body:    m.body,
+		Headers: m.headers,
+		Delay:   delay,
+	})
+}
+
+func (m *memoryMessage) GetBody() []byte {
+	return m.body
+}
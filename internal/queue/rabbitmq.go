@@ -1,7 +1,13 @@
 package queue
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"go_parser/internal/domain/queue"
 	"go_parser/internal/utils"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/rabbitmq/amqp091-go"
@@ -10,6 +16,18 @@ import (
 
 const rabbitService = "RabbitMQ"
 
+// Состояние соединения брокера для health-check
+const (
+	StateConnected    = "connected"
+	StateReconnecting = "reconnecting"
+	StateClosed       = "closed"
+)
+
+const (
+	reconnectBaseDelay = time.Second
+	reconnectMaxDelay  = 30 * time.Second
+)
+
 func ConnectToRabbitMQ(uri string) (*amqp.Connection, error) {
 	var conn *amqp.Connection
 	var err error
@@ -34,16 +52,386 @@ func CreateChannel(conn *amqp.Connection) (*amqp.Channel, error) {
 	return ch, nil
 }
 
-type Message struct {
-	msg amqp091.Delivery
+// Topology объявляет очереди и обменники на новом канале - при старте и после каждого переподключения.
+type Topology func(ch *amqp.Channel) error
+
+// RabbitBroker реализует queue.Broker поверх RabbitMQ.
+// Следит за соединением и каналом: при обрыве переподключается с экспоненциальной задержкой,
+// заново объявляет топологию и возобновляет подписки. Пока соединения нет, Publish блокируется.
+// Отложенная доставка идёт через очереди задержки из DeclareRetryTopology.
+type RabbitBroker struct {
+	uri      string
+	retry    RetryPolicy
+	prefetch int
+	topology Topology
+
+	mu    sync.RWMutex
+	conn  *amqp.Connection
+	ch    *amqp.Channel
+	state string
+	// ready закрыт, пока соединение установлено; при обрыве заменяется новым
+	ready     chan struct{}
+	consumers map[string]chan queue.WrapperMessage
+	// tags - consumer tag подписки на очередь в текущем канале
+	tags map[string]string
+	// stopped - после StopConsuming подписки не возобновляются при переподключении
+	stopped bool
+
+	done      chan struct{}
+	closeOnce sync.Once
+	forwarder sync.WaitGroup
+}
+
+// prefetch - сколько неподтверждённых сообщений брокер отдаёт потребителю; 0 - без ограничения
+func NewRabbitBroker(uri string, retry RetryPolicy, prefetch int, topology Topology) (*RabbitBroker, error) {
+	b := &RabbitBroker{
+		uri:       uri,
+		retry:     retry,
+		prefetch:  prefetch,
+		topology:  topology,
+		ready:     make(chan struct{}),
+		consumers: make(map[string]chan queue.WrapperMessage),
+		tags:      make(map[string]string),
+		done:      make(chan struct{}),
+	}
+
+	conn, err := ConnectToRabbitMQ(uri)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.setup(conn); err != nil {
+		return nil, err
+	}
+
+	go b.supervise()
+
+	return b, nil
+}
+
+// setup открывает канал, объявляет топологию и переводит брокер в состояние connected.
+func (b *RabbitBroker) setup(conn *amqp.Connection) error {
+	ch, err := CreateChannel(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	if err := ch.Qos(b.prefetch, 0, false); err != nil {
+		conn.Close()
+		return utils.NewError(rabbitService, err)
+	}
+
+	if b.topology != nil {
+		if err := b.topology(ch); err != nil {
+			conn.Close()
+			return utils.NewError(rabbitService, err)
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateClosed {
+		conn.Close()
+		return utils.NewError(rabbitService, ErrBrokerClosed)
+	}
+
+	b.conn, b.ch = conn, ch
+	for name, out := range b.consumers {
+		if b.stopped {
+			break
+		}
+		if err := b.startConsumer(ch, name, out); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	b.state = StateConnected
+	close(b.ready)
+
+	return nil
+}
+
+func (b *RabbitBroker) supervise() {
+	for {
+		b.mu.RLock()
+		connClosed := b.conn.NotifyClose(make(chan *amqp.Error, 1))
+		chClosed := b.ch.NotifyClose(make(chan *amqp.Error, 1))
+		ch := b.ch
+		b.mu.RUnlock()
+
+		var reason *amqp.Error
+		select {
+		case <-b.done:
+			return
+		case reason = <-connClosed:
+		case reason = <-chClosed:
+		}
+
+		select {
+		case <-b.done:
+			return
+		default:
+		}
+
+		utils.Logger.Printf("Соединение с RabbitMQ потеряно: %v", reason)
+		b.markDown(ch)
+
+		if !b.reconnect() {
+			return
+		}
+		utils.Logger.Println("Соединение с RabbitMQ восстановлено.")
+	}
+}
+
+func (b *RabbitBroker) reconnect() bool {
+	b.mu.RLock()
+	old := b.conn
+	b.mu.RUnlock()
+	// Если оборвался только канал, соединение нужно закрыть, чтобы переподключиться целиком
+	old.Close()
+
+	delay := reconnectBaseDelay
+	for {
+		select {
+		case <-b.done:
+			return false
+		case <-time.After(delay):
+		}
+
+		conn, err := amqp.Dial(b.uri)
+		if err == nil {
+			err = b.setup(conn)
+		}
+		if err == nil {
+			return true
+		}
+
+		utils.Logger.Printf("Ошибка переподключения к RabbitMQ: %v, следующая попытка через %s", err, delay)
+		delay = min(delay*2, reconnectMaxDelay)
+	}
+}
+
+// markDown переводит брокер в состояние reconnecting, если ch всё ещё текущий канал.
+func (b *RabbitBroker) markDown(ch *amqp.Channel) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.ch != ch || b.state != StateConnected {
+		return
+	}
+	b.state = StateReconnecting
+	b.ready = make(chan struct{})
+}
+
+func (b *RabbitBroker) State() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.state
+}
+
+// channel ждёт установленного соединения и возвращает текущий канал.
+func (b *RabbitBroker) channel(ctx context.Context) (*amqp.Channel, error) {
+	b.mu.RLock()
+	ready, state := b.ready, b.state
+	b.mu.RUnlock()
+
+	if state == StateClosed {
+		return nil, utils.NewError(rabbitService, ErrBrokerClosed)
+	}
+
+	select {
+	case <-ready:
+		b.mu.RLock()
+		defer b.mu.RUnlock()
+		return b.ch, nil
+	case <-b.done:
+		return nil, utils.NewError(rabbitService, ErrBrokerClosed)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (b *RabbitBroker) Publish(ctx context.Context, queueName string, msg queue.Publishing) error {
+	routingKey := queueName
+	var expiration string
+	// Очереди задержки есть только у очередей задач
+	if msg.Delay > 0 && msg.Exchange == "" {
+		routingKey = DelayQueueName(queueName, b.delayAttempt(msg.Delay))
+		expiration = strconv.FormatInt(msg.Delay.Milliseconds(), 10)
+	}
+
+	publishing := amqp.Publishing{
+		ContentType:  "application/json",
+		Body:         msg.Body,
+		DeliveryMode: amqp.Persistent,
+		Headers:      amqp.Table(msg.Headers),
+		Expiration:   expiration,
+		Priority:     msg.Priority,
+	}
+
+	for {
+		ch, err := b.channel(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = ch.PublishWithContext(ctx, msg.Exchange, routingKey, false, false, publishing)
+		if errors.Is(err, amqp.ErrClosed) {
+			// Соединение оборвалось - ждём переподключения и публикуем снова
+			b.markDown(ch)
+			continue
+		}
+		if err != nil {
+			return utils.NewError(rabbitService, err)
+		}
+		return nil
+	}
+}
+
+// delayAttempt выбирает очередь задержки, TTL которой не меньше delay,
+// иначе брокер вернёт сообщение раньше срока.
+func (b *RabbitBroker) delayAttempt(delay time.Duration) int {
+	attempt := 1
+	for attempt < b.retry.MaxAttempts-1 && b.retry.Ceiling(attempt) < delay {
+		attempt++
+	}
+	return attempt
+}
+
+// Consume подписывается на очередь. Канал сообщений переживает переподключения
+// и закрывается только в Close.
+func (b *RabbitBroker) Consume(queueName string) (<-chan queue.WrapperMessage, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateClosed || b.stopped {
+		return nil, utils.NewError(rabbitService, ErrBrokerClosed)
+	}
+	if out, ok := b.consumers[queueName]; ok {
+		return out, nil
+	}
+
+	out := make(chan queue.WrapperMessage)
+	b.consumers[queueName] = out
+
+	// Во время обрыва подписку оформит setup после переподключения
+	if b.state == StateConnected {
+		if err := b.startConsumer(b.ch, queueName, out); err != nil {
+			delete(b.consumers, queueName)
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// startConsumer подписывается на очередь в канале ch и пересылает доставки в out,
+// пока канал не закроется. Вызывать под mu.
+func (b *RabbitBroker) startConsumer(ch *amqp.Channel, queueName string, out chan queue.WrapperMessage) error {
+	// Тег задаём сами: по нему подписку отменяет StopConsuming
+	tag := fmt.Sprintf("%s-%d", queueName, time.Now().UnixNano())
+	deliveries, err := ch.Consume(
+		queueName, // Имя очереди
+		tag,       // consumer tag
+		false,     // autoAck (не подтверждать сообщения автоматически)
+		false,     // exclusive (очередь доступна для других потребителей)
+		false,     // noLocal (доставлять сообщения, отправленные тем же соединением)
+		false,     // noWait (ждать ответа от сервера)
+		nil,       // arguments (дополнительные аргументы)
+	)
+	if err != nil {
+		return utils.NewError(rabbitService, err)
+	}
+	b.tags[queueName] = tag
+
+	b.forwarder.Add(1)
+	go func() {
+		defer b.forwarder.Done()
+		for d := range deliveries {
+			select {
+			case out <- &Message{msg: d, broker: b, queue: queueName}:
+			case <-b.done:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// StopConsuming отменяет подписки. Сообщения, уже полученные от сервера, пересылаются
+// в каналы Consume, после чего каналы закрываются; неподтверждённые вернутся в очередь.
+func (b *RabbitBroker) StopConsuming() {
+	b.mu.Lock()
+	if b.stopped || b.state == StateClosed {
+		b.mu.Unlock()
+		return
+	}
+	b.stopped = true
+	ch, connected := b.ch, b.state == StateConnected
+	tags := make([]string, 0, len(b.tags))
+	for _, tag := range b.tags {
+		tags = append(tags, tag)
+	}
+	b.mu.Unlock()
+
+	// Во время обрыва подписок нет: старые доставки закрылись вместе с каналом
+	if connected {
+		for _, tag := range tags {
+			if err := ch.Cancel(tag, false); err != nil {
+				utils.Logger.Printf("[%s] ошибка отмены подписки %s: %v", rabbitService, tag, err)
+			}
+		}
+	}
+
+	go func() {
+		b.forwarder.Wait()
+		b.closeConsumers()
+	}()
 }
 
-func NewMessage(msg amqp091.Delivery) *Message {
-	return &Message{
-		msg: msg,
+// closeConsumers закрывает каналы Consume; повторный вызов ничего не делает.
+func (b *RabbitBroker) closeConsumers() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for name, out := range b.consumers {
+		close(out)
+		delete(b.consumers, name)
 	}
 }
 
+func (b *RabbitBroker) Close() error {
+	var err error
+	b.closeOnce.Do(func() {
+		close(b.done)
+
+		b.mu.Lock()
+		b.state = StateClosed
+		conn := b.conn
+		b.mu.Unlock()
+
+		if conn != nil {
+			err = conn.Close()
+		}
+
+		b.forwarder.Wait()
+		b.closeConsumers()
+	})
+
+	if err != nil && !errors.Is(err, amqp.ErrClosed) {
+		return utils.NewError(rabbitService, err)
+	}
+	return nil
+}
+
+type Message struct {
+	msg    amqp091.Delivery
+	broker *RabbitBroker
+	queue  string
+}
+
 func (m *Message) Success() {
 	m.msg.Ack(false)
 }
@@ -56,6 +444,21 @@ func (m *Message) Reject() {
 	m.msg.Reject(false)
 }
 
+// Requeue публикует копию сообщения с задержкой и подтверждает оригинал.
+func (m *Message) Requeue(delay time.Duration) error {
+	err := m.broker.Publish(context.Background(), m.queue, queue.Publishing{
+		Body:    m.msg.Body,
+		Headers: m.msg.Headers,
+		Delay:   delay,
+	})
+	if err != nil {
+		return err
+	}
+
+	m.Success()
+	return nil
+}
+
 func (m *Message) GetBody() []byte {
 	return m.msg.Body
 }
@@ -0,0 +1,99 @@
+package queue
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func NewRetryPolicy(maxAttempts int, baseDelay, maxDelay time.Duration) RetryPolicy {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	if maxDelay < baseDelay {
+		maxDelay = baseDelay
+	}
+
+	return RetryPolicy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   baseDelay,
+		MaxDelay:    maxDelay,
+	}
+}
+
+// Exhausted сообщает, что задача с retryCount повторами исчерпала все попытки.
+func (p RetryPolicy) Exhausted(retryCount int) bool {
+	return retryCount+1 >= p.MaxAttempts
+}
+
+// Ceiling - верхняя граница задержки перед попыткой attempt (1, 2, ...).
+func (p RetryPolicy) Ceiling(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// Backoff возвращает экспоненциальную задержку с jitter в диапазоне [ceiling/2, ceiling].
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	ceiling := p.Ceiling(attempt)
+	half := ceiling / 2
+	if half <= 0 {
+		return ceiling
+	}
+	return half + rand.N(half+1)
+}
+
+func DelayQueueName(queueName string, attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", queueName, attempt)
+}
+
+func DeadLetterQueueName(queueName string) string {
+	return queueName + ".dlq"
+}
+
+// DeclareRetryTopology объявляет очереди задержки (по одной на попытку) и dead-letter очередь.
+// Очередь задержки не имеет потребителей: сообщение лежит в ней до истечения TTL
+// и через DLX возвращается в основную очередь.
+func DeclareRetryTopology(ch *amqp.Channel, queueName string, p RetryPolicy) error {
+	for attempt := 1; attempt < p.MaxAttempts; attempt++ {
+		_, err := ch.QueueDeclare(
+			DelayQueueName(queueName, attempt),
+			false,
+			false,
+			false,
+			false,
+			amqp.Table{
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queueName,
+				"x-message-ttl":             p.Ceiling(attempt).Milliseconds(),
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("ошибка объявления очереди задержки %d: %w", attempt, err)
+		}
+	}
+
+	_, err := ch.QueueDeclare(
+		DeadLetterQueueName(queueName),
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка объявления dead-letter очереди: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package records
+
+import (
+	"context"
+	"fmt"
+	"go_parser/internal/database"
+	"go_parser/internal/domain/record"
+	"go_parser/internal/utils"
+	"time"
+)
+
+// Что произошло с записью при сохранении
+const (
+	OutcomeCreated   = "created"
+	OutcomeUnchanged = "unchanged"
+	OutcomeChanged   = "changed"
+)
+
+// maxSaveAttempts - сколько раз перечитывать запись, если её параллельно обновил другой воркер
+const maxSaveAttempts = 3
+
+// Saved - итог сохранения. Previous заполнен, только если содержимое изменилось.
+type Saved struct {
+	Outcome  string
+	Record   *record.Record
+	Previous *record.History
+}
+
+// ChangeDetector получает обе версии, когда содержимое записи изменилось
+type ChangeDetector interface {
+	Detect(ctx context.Context, prev *record.History, cur *record.Record) error
+}
+
+// Store хранит по одной записи на пару (URL, план). Повторный обход с тем же
+// содержимым только обновляет parsed_at, изменившееся содержимое увеличивает
+// версию, а прежняя версия уходит в историю.
+type Store struct {
+	repo    database.Repository[*record.Record]
+	history database.Repository[*record.History]
+	changes ChangeDetector
+}
+
+// changes может быть nil - тогда изменения не отслеживаются
+func NewStore(repo database.Repository[*record.Record], history database.Repository[*record.History], changes ChangeDetector) *Store {
+	return &Store{
+		repo:    repo,
+		history: history,
+		changes: changes,
+	}
+}
+
+func (s *Store) Save(ctx context.Context, r *record.Record) (*Saved, error) {
+	hash, err := record.HashData(r.Data)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка вычисления хэша данных: %w", err)
+	}
+	r.ContentHash = hash
+
+	for attempt := 0; attempt < maxSaveAttempts; attempt++ {
+		// Записи об ошибках без хэша в версионировании не участвуют
+		existing, err := s.repo.FindOne(ctx, database.Filter{
+			"url":          r.URL,
+			"plan":         r.PlanName,
+			"content_hash": map[string]interface{}{"exists": true},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var saved *Saved
+		switch {
+		case existing == nil:
+			saved, err = s.create(ctx, r)
+		case existing.ContentHash == hash:
+			saved, err = s.touch(ctx, existing, r)
+		default:
+			saved, err = s.replace(ctx, existing, r)
+		}
+		if err != nil || saved != nil {
+			return saved, err
+		}
+		// Запись успел создать или изменить другой воркер - сравниваем заново
+	}
+
+	return nil, fmt.Errorf("запись %s (%s) изменяется параллельно, попытки исчерпаны", r.URL, r.PlanName)
+}
+
+// SaveError сохраняет запись об ошибке. Такие записи не версионируются.
+func (s *Store) SaveError(ctx context.Context, r *record.Record) error {
+	now := time.Now()
+	r.CreatedAt = now
+	r.UpdatedAt = now
+	return s.repo.Create(ctx, r)
+}
+
+func (s *Store) create(ctx context.Context, r *record.Record) (*Saved, error) {
+	now := time.Now()
+	r.Version = 1
+	r.CreatedAt = now
+	r.UpdatedAt = now
+
+	if err := s.repo.Create(ctx, r); err != nil {
+		if database.IsDuplicate(err) {
+			r.SetID("")
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &Saved{Outcome: OutcomeCreated, Record: r}, nil
+}
+
+func (s *Store) touch(ctx context.Context, existing, r *record.Record) (*Saved, error) {
+	if _, err := s.repo.UpdateOne(ctx,
+		database.Filter{"_id": existing.GetID()},
+		// domain дописывается записям, сохранённым до его заполнения
+		map[string]interface{}{"parsed_at": r.ParsedAt, "domain": r.Domain},
+	); err != nil {
+		return nil, err
+	}
+
+	existing.ParsedAt = r.ParsedAt
+	existing.Domain = r.Domain
+	return &Saved{Outcome: OutcomeUnchanged, Record: existing}, nil
+}
+
+// replace архивирует текущую версию и записывает новую. Версия меняется
+// compare-and-set, поэтому из двух параллельных обновлений пройдёт одно.
+func (s *Store) replace(ctx context.Context, existing, r *record.Record) (*Saved, error) {
+	now := time.Now()
+	prev := record.NewHistory(existing, now)
+	if err := s.history.Create(ctx, prev); err != nil && !database.IsDuplicate(err) {
+		return nil, fmt.Errorf("ошибка архивации версии %d: %w", existing.Version, err)
+	}
+
+	ok, err := s.repo.UpdateOne(ctx,
+		database.Filter{"_id": existing.GetID(), "version": existing.Version},
+		map[string]interface{}{
+			"job_id":       r.JobID,
+			"domain":       r.Domain,
+			"depth":        r.Depth,
+			"data":         r.Data,
+			"content_hash": r.ContentHash,
+			"version":      existing.Version + 1,
+			"parsed_at":    r.ParsedAt,
+			"updated_at":   now,
+		},
+	)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	r.SetID(existing.GetID())
+	r.Version = existing.Version + 1
+	r.CreatedAt = existing.CreatedAt
+	r.UpdatedAt = now
+
+	// Новая версия уже сохранена, поэтому ошибка событий задачу не роняет
+	if s.changes != nil {
+		if err := s.changes.Detect(ctx, prev, r); err != nil {
+			utils.Logger.Printf("Ошибка обработки изменений %s: %v", r.URL, err)
+		}
+	}
+
+	return &Saved{Outcome: OutcomeChanged, Record: r, Previous: prev}, nil
+}
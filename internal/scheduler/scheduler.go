@@ -0,0 +1,197 @@
+package scheduler
+
+import (
+	"context"
+	"go_parser/internal/database"
+	"go_parser/internal/domain/job"
+	"go_parser/internal/domain/schedule"
+	"go_parser/internal/domain/task"
+	"go_parser/internal/utils"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// launchAttempts - сколько раз run пытается поставить захваченный запуск
+	launchAttempts   = 3
+	launchRetryDelay = time.Second
+)
+
+type JobStarter interface {
+	Start(ctx context.Context, seed *task.Task, budget job.Budget) (*job.Job, error)
+	Fail(ctx context.Context, id string, reason error) error
+}
+
+type TaskPublisher interface {
+	Submit(ctx context.Context, t *task.Task) error
+}
+
+// Next возвращает время следующего запуска после after. Поддерживаются
+// стандартные 5-полевые выражения, @hourly/@daily/@every и префикс CRON_TZ=.
+func Next(expr string, after time.Time) (time.Time, error) {
+	sched, err := cron.ParseStandard(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// MongoDB хранит время с точностью до миллисекунд, а next_run сравнивается на равенство
+	return sched.Next(after).UTC().Truncate(time.Millisecond), nil
+}
+
+// Scheduler периодически ищет расписания, время которых наступило, и запускает по ним задания.
+// Запуск захватывается compare-and-set по next_run, поэтому при нескольких инстансах
+// каждый запуск ставится в очередь ровно один раз.
+type Scheduler struct {
+	repo      database.Repository[*schedule.Schedule]
+	jobs      JobStarter
+	publisher TaskPublisher
+	interval  time.Duration
+	retry     time.Duration
+	quit      chan struct{}
+	done      chan struct{}
+}
+
+func NewScheduler(
+	repo database.Repository[*schedule.Schedule],
+	jobs JobStarter,
+	publisher TaskPublisher,
+	interval time.Duration,
+) *Scheduler {
+	return &Scheduler{
+		repo:      repo,
+		jobs:      jobs,
+		publisher: publisher,
+		interval:  interval,
+		retry:     launchRetryDelay,
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (s *Scheduler) Start() {
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.tick(context.Background())
+
+			select {
+			case <-ticker.C:
+			case <-s.quit:
+				return
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) Stop() {
+	close(s.quit)
+	<-s.done
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	now := time.Now().UTC()
+
+	due, err := s.repo.Find(ctx, database.Filter{
+		"enabled":  true,
+		"next_run": map[string]interface{}{"lte": now},
+	}, nil)
+	if err != nil {
+		utils.Logger.Printf("Ошибка чтения расписаний: %v", err)
+		return
+	}
+
+	for _, sc := range due {
+		s.run(ctx, sc, now)
+	}
+}
+
+// run захватывает запуск, сдвигая next_run, и ставит seed-задачу. Пропущенные,
+// пока парсер не работал, запуски не догоняются: выполняется один, следующий - по cron.
+// next_run сдвигается до постановки задачи, иначе запуск взяли бы несколько инстансов,
+// поэтому ошибку jobs.Start или Submit run повторяет в том же тике до launchAttempts раз.
+// Если все попытки неудачны, запуск теряется: остаётся только last_error, следующий - по cron.
+func (s *Scheduler) run(ctx context.Context, sc *schedule.Schedule, now time.Time) {
+	next, err := Next(sc.Cron, now)
+	if err != nil {
+		utils.Logger.Printf("Расписание %s отключено: невалидный cron %q: %v", sc.GetID(), sc.Cron, err)
+		s.update(ctx, sc.GetID(), map[string]interface{}{
+			"enabled":    false,
+			"last_error": err.Error(),
+		})
+		return
+	}
+
+	claimed, err := s.repo.UpdateOne(ctx,
+		database.Filter{"_id": sc.GetID(), "next_run": sc.NextRun},
+		map[string]interface{}{
+			"next_run":   next,
+			"last_run":   now,
+			"updated_at": now,
+		},
+	)
+	if err != nil {
+		utils.Logger.Printf("Ошибка захвата расписания %s: %v", sc.GetID(), err)
+		return
+	}
+	if !claimed {
+		// Запуск уже взял другой инстанс
+		return
+	}
+
+	var jobID string
+	for attempt := 1; ; attempt++ {
+		if jobID, err = s.launch(ctx, sc); err == nil || attempt == launchAttempts {
+			break
+		}
+		utils.Logger.Printf("Попытка %d запуска по расписанию %s не удалась: %v", attempt, sc.GetID(), err)
+		select {
+		case <-time.After(s.retry):
+		case <-s.quit:
+		}
+	}
+
+	lastError := ""
+	if err != nil {
+		lastError = err.Error()
+		utils.Logger.Printf("Ошибка запуска по расписанию %s: %v", sc.GetID(), err)
+	} else {
+		utils.Logger.Printf("Запуск по расписанию %s: задание %s, следующий запуск %s", sc.GetID(), jobID, next.Format(time.RFC3339))
+	}
+
+	s.update(ctx, sc.GetID(), map[string]interface{}{
+		"last_job_id": jobID,
+		"last_error":  lastError,
+	})
+}
+
+func (s *Scheduler) launch(ctx context.Context, sc *schedule.Schedule) (string, error) {
+	seed := &task.Task{
+		URL:      sc.URL,
+		Plan:     sc.Plan,
+		MaxDepth: sc.MaxDepth,
+		Options:  sc.Options,
+	}
+
+	if _, err := s.jobs.Start(ctx, seed, sc.Budget); err != nil {
+		return "", err
+	}
+
+	if err := s.publisher.Submit(ctx, seed); err != nil {
+		if failErr := s.jobs.Fail(ctx, seed.JobID, err); failErr != nil {
+			utils.Logger.Printf("Ошибка обновления задания %s: %v", seed.JobID, failErr)
+		}
+		return seed.JobID, err
+	}
+
+	return seed.JobID, nil
+}
+
+func (s *Scheduler) update(ctx context.Context, id string, fields map[string]interface{}) {
+	if _, err := s.repo.UpdateOne(ctx, database.Filter{"_id": id}, fields); err != nil {
+		utils.Logger.Printf("Ошибка обновления расписания %s: %v", id, err)
+	}
+}
@@ -0,0 +1,155 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_parser/internal/database"
+	"go_parser/internal/domain/job"
+	"go_parser/internal/domain/schedule"
+	"go_parser/internal/domain/task"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeJobs struct {
+	mu      sync.Mutex
+	started int
+	failed  []string
+}
+
+func (f *fakeJobs) Start(ctx context.Context, seed *task.Task, budget job.Budget) (*job.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started++
+	seed.JobID = fmt.Sprintf("job-%d", f.started)
+	return &job.Job{}, nil
+}
+
+func (f *fakeJobs) Fail(ctx context.Context, id string, reason error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failed = append(f.failed, id)
+	return nil
+}
+
+type fakePublisher struct {
+	mu        sync.Mutex
+	failures  int
+	submitted []*task.Task
+}
+
+func (f *fakePublisher) Submit(ctx context.Context, t *task.Task) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("брокер недоступен")
+	}
+	f.submitted = append(f.submitted, t)
+	return nil
+}
+
+func newDueSchedule(t *testing.T, repo database.Repository[*schedule.Schedule]) *schedule.Schedule {
+	t.Helper()
+
+	sc := &schedule.Schedule{
+		Name:    "hn",
+		Cron:    "@hourly",
+		URL:     "https://news.ycombinator.com/news",
+		Plan:    "hackernews",
+		Enabled: true,
+		NextRun: time.Now().UTC().Add(-time.Minute).Truncate(time.Millisecond),
+	}
+	if err := repo.Create(context.Background(), sc); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return sc
+}
+
+func TestTickClaimsRunOnce(t *testing.T) {
+	ctx := context.Background()
+	repo := database.NewMemoryRepository[*schedule.Schedule]()
+	sc := newDueSchedule(t, repo)
+
+	jobs := &fakeJobs{}
+	publisher := &fakePublisher{}
+	schedulers := []*Scheduler{
+		NewScheduler(repo, jobs, publisher, time.Minute),
+		NewScheduler(repo, jobs, publisher, time.Minute),
+	}
+
+	var wg sync.WaitGroup
+	for _, s := range schedulers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.tick(ctx)
+		}()
+	}
+	wg.Wait()
+
+	if jobs.started != 1 || len(publisher.submitted) != 1 {
+		t.Fatalf("заданий %d, задач %d, want по одному", jobs.started, len(publisher.submitted))
+	}
+
+	got, err := repo.Get(ctx, sc.GetID())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !got.NextRun.After(time.Now()) {
+		t.Errorf("next_run = %s, want в будущем", got.NextRun)
+	}
+	if got.LastJobID != "job-1" || got.LastError != "" {
+		t.Errorf("last_job_id = %q, last_error = %q", got.LastJobID, got.LastError)
+	}
+
+	// Запуск уже выполнен, повторный тик ничего не ставит
+	schedulers[0].tick(ctx)
+	if jobs.started != 1 {
+		t.Errorf("повторный тик создал задание: %d", jobs.started)
+	}
+}
+
+func TestRunRetriesLaunch(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		wantJobs  int
+		wantFail  int
+		wantError bool
+	}{
+		{name: "со второй попытки", failures: 1, wantJobs: 2, wantFail: 1},
+		{name: "все попытки неудачны", failures: launchAttempts, wantJobs: launchAttempts, wantFail: launchAttempts, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := database.NewMemoryRepository[*schedule.Schedule]()
+			sc := newDueSchedule(t, repo)
+
+			jobs := &fakeJobs{}
+			publisher := &fakePublisher{failures: tt.failures}
+			s := NewScheduler(repo, jobs, publisher, time.Minute)
+			s.retry = time.Millisecond
+			s.tick(ctx)
+
+			if jobs.started != tt.wantJobs || len(jobs.failed) != tt.wantFail {
+				t.Errorf("заданий %d, проваленных %d, want %d и %d", jobs.started, len(jobs.failed), tt.wantJobs, tt.wantFail)
+			}
+
+			got, err := repo.Get(ctx, sc.GetID())
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if (got.LastError != "") != tt.wantError {
+				t.Errorf("last_error = %q", got.LastError)
+			}
+			if !got.NextRun.After(time.Now()) {
+				t.Errorf("next_run = %s, want в будущем", got.NextRun)
+			}
+		})
+	}
+}
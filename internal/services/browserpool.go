@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_parser/internal/utils"
+	"sync"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+const browserService = "BrowserPool"
+
+var ErrPoolClosed = errors.New("пул браузеров закрыт")
+
+type pooledBrowser struct {
+	// browser == nil - слот пуст, браузер запускается при следующей выдаче
+	browser playwright.Browser
+	pages   int
+}
+
+// BrowserPool держит size долгоживущих браузеров и выдаёт по одному изолированному
+// BrowserContext на браузер. Браузер перезапускается после maxPages страниц или при падении.
+// Перезапуск откладывается до выдачи слота: если запуск не удался, слот остаётся в пуле
+// пустым и следующий Acquire попробует снова.
+type BrowserPool struct {
+	pw       *playwright.Playwright
+	maxPages int
+	slots    chan *pooledBrowser
+	quit     chan struct{}
+	size     int
+
+	mu     sync.Mutex
+	closed bool
+	// inUse - выданные браузеры; Close ждёт их возврата перед остановкой playwright
+	inUse sync.WaitGroup
+}
+
+func NewBrowserPool(size, maxPages int) (*BrowserPool, error) {
+	if size < 1 {
+		size = 1
+	}
+
+	pw, err := playwright.Run()
+	if err != nil {
+		return nil, utils.NewError(browserService, fmt.Errorf("не удалось запустить playwright: %w", err))
+	}
+
+	p := &BrowserPool{
+		pw:       pw,
+		maxPages: maxPages,
+		slots:    make(chan *pooledBrowser, size),
+		quit:     make(chan struct{}),
+		size:     size,
+	}
+
+	for i := 0; i < size; i++ {
+		browser, err := p.launch()
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.slots <- &pooledBrowser{browser: browser}
+	}
+
+	return p, nil
+}
+
+func (p *BrowserPool) launch() (playwright.Browser, error) {
+	browser, err := p.pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
+		Headless: playwright.Bool(true),
+	})
+	if err != nil {
+		return nil, utils.NewError(browserService, fmt.Errorf("ошибка запуска браузера: %w", err))
+	}
+
+	browser.OnDisconnected(func(playwright.Browser) {
+		utils.Logger.Printf("[%s] браузер отключился", browserService)
+	})
+
+	return browser, nil
+}
+
+// Acquire блокируется до появления свободного браузера или отмены ctx и возвращает новый контекст.
+// release закрывает контекст и возвращает браузер в пул; вызывать обязательно.
+func (p *BrowserPool) Acquire(ctx context.Context) (playwright.BrowserContext, func(), error) {
+	var pb *pooledBrowser
+	select {
+	case pb = <-p.slots:
+	case <-p.quit:
+		return nil, nil, ErrPoolClosed
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.closeBrowser(pb)
+		return nil, nil, ErrPoolClosed
+	}
+	p.inUse.Add(1)
+	p.mu.Unlock()
+
+	if err := p.ensure(pb); err != nil {
+		p.release(pb)
+		return nil, nil, err
+	}
+
+	bctx, err := pb.browser.NewContext()
+	if err != nil {
+		p.release(pb)
+		return nil, nil, utils.NewError(browserService, fmt.Errorf("ошибка создания контекста: %w", err))
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			if err := bctx.Close(); err != nil && pb.browser.IsConnected() {
+				utils.Logger.Printf("[%s] ошибка закрытия контекста: %v", browserService, err)
+			}
+			pb.pages++
+			p.release(pb)
+		})
+	}
+
+	return bctx, release, nil
+}
+
+// ensure запускает браузер в пустом слоте или вместо упавшего.
+func (p *BrowserPool) ensure(pb *pooledBrowser) error {
+	if pb.browser != nil && pb.browser.IsConnected() {
+		return nil
+	}
+	p.closeBrowser(pb)
+
+	browser, err := p.launch()
+	if err != nil {
+		return err
+	}
+	pb.browser = browser
+	pb.pages = 0
+	return nil
+}
+
+// release возвращает слот в пул. Отработавший или упавший браузер закрывается,
+// новый запустит следующий Acquire - после Close запускать его уже некому.
+func (p *BrowserPool) release(pb *pooledBrowser) {
+	defer p.inUse.Done()
+
+	if pb.browser != nil && (!pb.browser.IsConnected() || (p.maxPages > 0 && pb.pages >= p.maxPages)) {
+		utils.Logger.Printf("[%s] перезапуск браузера: страниц %d, подключён %v",
+			browserService, pb.pages, pb.browser.IsConnected())
+		p.closeBrowser(pb)
+	}
+
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+
+	if closed {
+		p.closeBrowser(pb)
+		return
+	}
+	// Слотов в пуле не больше ёмкости канала, поэтому отправка не блокируется
+	p.slots <- pb
+}
+
+func (p *BrowserPool) closeBrowser(pb *pooledBrowser) {
+	if pb.browser == nil {
+		return
+	}
+	if pb.browser.IsConnected() {
+		if err := pb.browser.Close(); err != nil {
+			utils.Logger.Printf("[%s] ошибка закрытия браузера: %v", browserService, err)
+		}
+	}
+	pb.browser = nil
+}
+
+// Size - число браузеров в пуле, Available - число свободных.
+func (p *BrowserPool) Size() int {
+	return p.size
+}
+
+func (p *BrowserPool) Available() int {
+	return len(p.slots)
+}
+
+// Close закрывает свободные браузеры, дожидается возврата занятых и останавливает playwright.
+func (p *BrowserPool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.quit)
+	p.mu.Unlock()
+
+	p.drain()
+	p.inUse.Wait()
+	// release, начавшийся до закрытия, мог успеть вернуть слот после первого drain
+	p.drain()
+
+	if p.pw == nil {
+		return nil
+	}
+	return p.pw.Stop()
+}
+
+func (p *BrowserPool) drain() {
+	for {
+		select {
+		case pb := <-p.slots:
+			p.closeBrowser(pb)
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_parser/internal/domain/fetcher"
+	"go_parser/internal/utils"
+	"net/http"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+const browserFetcherService = "BrowserFetcher"
+
+// BrowserFetcher загружает страницу в браузере из пула и отдаёт итоговый DOM.
+type BrowserFetcher struct {
+	browsers *BrowserPool
+}
+
+func NewBrowserFetcher(browsers *BrowserPool) *BrowserFetcher {
+	return &BrowserFetcher{
+		browsers: browsers,
+	}
+}
+
+func (f *BrowserFetcher) Name() string {
+	return "browser"
+}
+
+func (f *BrowserFetcher) Fetch(ctx context.Context, url string) (*fetcher.Response, error) {
+	bctx, release, err := f.browsers.Acquire(ctx)
+	if err != nil {
+		return nil, utils.Transient(err)
+	}
+	defer release()
+
+	page, err := bctx.NewPage()
+	if err != nil {
+		return nil, utils.Transient(utils.NewError(browserFetcherService, fmt.Errorf("ошибка создания страницы: %w", err)))
+	}
+	defer page.Close()
+
+	// playwright не принимает context: при отмене закрываем страницу, это прерывает навигацию
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			page.Close()
+		case <-done:
+		}
+	}()
+
+	opts := playwright.PageGotoOptions{
+		WaitUntil: playwright.WaitUntilStateNetworkidle,
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		opts.Timeout = playwright.Float(float64(max(time.Until(deadline).Milliseconds(), 1)))
+	}
+
+	resp, err := page.Goto(url, opts)
+	if err != nil {
+		return nil, utils.Transient(utils.NewError(browserFetcherService, fmt.Errorf("ошибка навигации: %w", ctxErr(ctx, err))))
+	}
+
+	content, err := page.Content()
+	if err != nil {
+		return nil, utils.Transient(utils.NewError(browserFetcherService, fmt.Errorf("ошибка получения содержимого: %w", ctxErr(ctx, err))))
+	}
+
+	result := &fetcher.Response{
+		StatusCode: http.StatusOK,
+		Headers:    http.Header{},
+		FinalURL:   page.URL(),
+		Body:       []byte(content),
+	}
+
+	// Goto возвращает nil для навигации внутри страницы (например, по якорю)
+	if resp != nil {
+		result.StatusCode = resp.Status()
+		if headers, err := resp.AllHeaders(); err == nil {
+			for k, v := range headers {
+				result.Headers.Set(k, v)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// ctxErr приводит ошибки закрытой страницы и таймаута playwright к ошибкам context,
+// чтобы воркер распознал их через errors.Is.
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w: %v", ctx.Err(), err)
+	}
+	if errors.Is(err, playwright.ErrTimeout) {
+		return fmt.Errorf("%w: %v", context.DeadlineExceeded, err)
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go_parser/internal/domain/fetcher"
+	"go_parser/internal/utils"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	httpFetcherService = "HTTPFetcher"
+	maxBodySize        = 10 << 20
+)
+
+type HTTPFetcher struct {
+	client    *http.Client
+	userAgent string
+}
+
+func NewHTTPFetcher(timeout time.Duration, userAgent string) *HTTPFetcher {
+	return &HTTPFetcher{
+		client: &http.Client{
+			Timeout: timeout,
+		},
+		userAgent: userAgent,
+	}
+}
+
+func (f *HTTPFetcher) Name() string {
+	return "http"
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, url string) (*fetcher.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, utils.InvalidTask(utils.NewError(httpFetcherService, err))
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, utils.Transient(utils.NewError(httpFetcherService, err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, utils.Transient(utils.NewError(httpFetcherService, fmt.Errorf("ошибка чтения тела ответа: %w", err)))
+	}
+
+	return &fetcher.Response{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header,
+		FinalURL:   resp.Request.URL.String(),
+		Body:       body,
+	}, nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"go_parser/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPFetcherFetch(t *testing.T) {
+	var gotUA, gotAccept string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		gotUA = r.Header.Get("User-Agent")
+		gotAccept = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><title>ok</title></html>"))
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := NewHTTPFetcher(5*time.Second, "test-agent")
+
+	tests := []struct {
+		name      string
+		path      string
+		wantCode  int
+		wantFinal string
+		wantBody  string
+	}{
+		{name: "страница", path: "/page", wantCode: http.StatusOK, wantFinal: "/page", wantBody: "<title>ok</title>"},
+		{name: "редирект", path: "/old", wantCode: http.StatusOK, wantFinal: "/page", wantBody: "<title>ok</title>"},
+		{name: "404 без ошибки", path: "/missing", wantCode: http.StatusNotFound, wantFinal: "/missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := f.Fetch(context.Background(), srv.URL+tt.path)
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("StatusCode = %d, want %d", resp.StatusCode, tt.wantCode)
+			}
+			if resp.FinalURL != srv.URL+tt.wantFinal {
+				t.Errorf("FinalURL = %q, want %q", resp.FinalURL, srv.URL+tt.wantFinal)
+			}
+			if !strings.Contains(string(resp.Body), tt.wantBody) {
+				t.Errorf("Body = %q, want %q", resp.Body, tt.wantBody)
+			}
+		})
+	}
+
+	if gotUA != "test-agent" {
+		t.Errorf("User-Agent = %q, want test-agent", gotUA)
+	}
+	if !strings.HasPrefix(gotAccept, "text/html") {
+		t.Errorf("Accept = %q", gotAccept)
+	}
+}
+
+func TestHTTPFetcherErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		url     string
+		want    utils.Category
+	}{
+		{name: "таймаут", timeout: 50 * time.Millisecond, url: srv.URL, want: utils.CategoryTransient},
+		{name: "невалидный URL", timeout: time.Second, url: "http://[::1", want: utils.CategoryInvalidTask},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHTTPFetcher(tt.timeout, "test-agent").Fetch(context.Background(), tt.url)
+			if err == nil {
+				t.Fatal("ожидалась ошибка")
+			}
+			if got := utils.CategoryOf(err); got != tt.want {
+				t.Errorf("категория = %s, want %s (%v)", got, tt.want, err)
+			}
+		})
+	}
+}
@@ -1,32 +1,23 @@
 package services
 
 import (
+	"context"
 	"go_parser/internal/utils"
 
 	"github.com/playwright-community/playwright-go"
 )
 
-func GetFullPage(url string) (string, error) {
-	utils.Logger.Println("Запуск Playwright...")
-	pw, err := playwright.Run()
+func (p *BrowserPool) GetFullPage(ctx context.Context, url string) (string, error) {
+	utils.Logger.Println("Получение контекста из пула браузеров...")
+	bctx, release, err := p.Acquire(ctx)
 	if err != nil {
 		return "", err
 	}
-	defer pw.Stop()
-	utils.Logger.Println("Playwright успешно запущен.")
-
-	utils.Logger.Println("Запуск headless-браузера...")
-	browser, err := pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
-		Headless: playwright.Bool(true),
-	})
-	if err != nil {
-		return "", err
-	}
-	defer browser.Close()
-	utils.Logger.Println("Браузер успешно запущен.")
+	defer release()
+	utils.Logger.Println("Контекст браузера получен.")
 
 	utils.Logger.Println("Создание новой страницы...")
-	page, err := browser.NewPage()
+	page, err := bctx.NewPage()
 	if err != nil {
 		return "", err
 	}
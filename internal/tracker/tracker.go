@@ -0,0 +1,174 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"go_parser/internal/database"
+	"go_parser/internal/domain/task"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxTransitionAttempts - сколько раз перечитывать задачу, если её статус параллельно сменили
+const maxTransitionAttempts = 5
+
+// JobProgress ведёт счётчики задания по переходам статусов его задач
+type JobProgress interface {
+	Transition(ctx context.Context, jobID, from, to string) error
+}
+
+type Tracker struct {
+	repo database.Repository[*task.Task]
+	jobs JobProgress
+}
+
+func NewTracker(repo database.Repository[*task.Task], jobs JobProgress) *Tracker {
+	return &Tracker{
+		repo: repo,
+		jobs: jobs,
+	}
+}
+
+// Pending сохраняет задачу в pending. Уже сохранённая задача (повторная публикация)
+// обновляется, и счётчики задания переводятся из её прежнего статуса.
+func (t *Tracker) Pending(ctx context.Context, tk *task.Task) error {
+	return t.transition(ctx, tk, task.StatusPending, nil)
+}
+
+func (t *Tracker) Running(ctx context.Context, tk *task.Task) error {
+	return t.transition(ctx, tk, task.StatusRunning, nil)
+}
+
+func (t *Tracker) Retrying(ctx context.Context, tk *task.Task, reason error) error {
+	return t.transition(ctx, tk, task.StatusRetrying, reason)
+}
+
+func (t *Tracker) Succeeded(ctx context.Context, tk *task.Task) error {
+	return t.transition(ctx, tk, task.StatusSucceeded, nil)
+}
+
+func (t *Tracker) Failed(ctx context.Context, tk *task.Task, reason error) error {
+	return t.transition(ctx, tk, task.StatusFailed, reason)
+}
+
+func (t *Tracker) Rejected(ctx context.Context, tk *task.Task, reason error) error {
+	return t.transition(ctx, tk, task.StatusRejected, reason)
+}
+
+// Paused откладывает задачу до снятия задания с паузы.
+func (t *Tracker) Paused(ctx context.Context, tk *task.Task) error {
+	return t.transition(ctx, tk, task.StatusPaused, nil)
+}
+
+func (t *Tracker) Cancelled(ctx context.Context, tk *task.Task) error {
+	return t.transition(ctx, tk, task.StatusCancelled, nil)
+}
+
+// Requeued возвращает сохранённую задачу в pending перед повторной публикацией.
+func (t *Tracker) Requeued(ctx context.Context, tk *task.Task) error {
+	return t.transition(ctx, tk, task.StatusPending, nil)
+}
+
+// Unpark переводит отложенную задачу в pending перед повторной публикацией. Переход
+// выполняется compare-and-set по статусу paused: из параллельных resume и воркера,
+// заметившего снятие паузы, задачу опубликует только получивший true.
+func (t *Tracker) Unpark(ctx context.Context, tk *task.Task) (bool, error) {
+	now := time.Now()
+	ok, err := t.repo.UpdateOne(ctx,
+		database.Filter{"_id": tk.GetID(), "status": task.StatusPaused},
+		map[string]interface{}{
+			"status":     task.StatusPending,
+			"error":      "",
+			"updated_at": now,
+		},
+	)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	tk.Status = task.StatusPending
+	tk.Error = ""
+	tk.UpdatedAt = now
+	return true, t.jobs.Transition(ctx, tk.JobID, task.StatusPaused, task.StatusPending)
+}
+
+// transition меняет статус задачи compare-and-set по статусу, прочитанному из базы,
+// и только после успешной замены переносит счётчики задания. Если статус успел
+// поменять другой воркер или отмена задания, задача перечитывается.
+func (t *Tracker) transition(ctx context.Context, tk *task.Task, status string, reason error) error {
+	tk.Status = status
+	tk.UpdatedAt = time.Now()
+	tk.Error = ""
+	if reason != nil {
+		tk.Error = reason.Error()
+	}
+
+	// Задачи, опубликованные до появления трекера, приходят без ID или без документа
+	if tk.GetID() == "" {
+		tk.SetID(primitive.NewObjectID().Hex())
+		if err := t.create(ctx, tk); err != nil {
+			return err
+		}
+		return t.jobs.Transition(ctx, tk.JobID, "", status)
+	}
+
+	fields, err := taskFields(tk)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < maxTransitionAttempts; attempt++ {
+		existing, err := t.repo.Get(ctx, tk.GetID())
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			err := t.create(ctx, tk)
+			if err == nil {
+				return t.jobs.Transition(ctx, tk.JobID, "", status)
+			}
+			if !database.IsDuplicate(err) {
+				return err
+			}
+			// Документ успел создать параллельный вызов - перечитываем
+			continue
+		}
+
+		// Предыдущий статус берём из базы: в сообщении он мог устареть
+		ok, err := t.repo.UpdateOne(ctx,
+			database.Filter{"_id": tk.GetID(), "status": existing.Status},
+			fields,
+		)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return t.jobs.Transition(ctx, tk.JobID, existing.Status, status)
+		}
+	}
+
+	return fmt.Errorf("статус задачи %s меняется параллельно, попытки исчерпаны", tk.GetID())
+}
+
+// taskFields - поля задачи для $set, как их записал бы Update, без _id.
+func taskFields(tk *task.Task) (map[string]interface{}, error) {
+	raw, err := bson.Marshal(tk)
+	if err != nil {
+		return nil, err
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "_id")
+	return fields, nil
+}
+
+func (t *Tracker) create(ctx context.Context, tk *task.Task) error {
+	if tk.CreatedAt.IsZero() {
+		tk.CreatedAt = tk.UpdatedAt
+	}
+	return t.repo.Create(ctx, tk)
+}
@@ -0,0 +1,157 @@
+package urlnorm
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// DefaultTrackingParams - параметры, не влияющие на содержимое страницы.
+// Шаблон с "*" на конце совпадает по префиксу.
+var DefaultTrackingParams = []string{
+	"utm_*",
+	"fbclid",
+	"gclid",
+	"yclid",
+	"dclid",
+	"msclkid",
+	"mc_cid",
+	"mc_eid",
+	"_ga",
+	"_hsenc",
+	"_hsmi",
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+type Normalizer struct {
+	exact    map[string]bool
+	prefixes []string
+}
+
+func New(trackingParams []string) *Normalizer {
+	n := &Normalizer{
+		exact: make(map[string]bool),
+	}
+
+	for _, p := range trackingParams {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			n.prefixes = append(n.prefixes, prefix)
+			continue
+		}
+		n.exact[p] = true
+	}
+
+	return n
+}
+
+// Resolve разрешает ref (относительный, protocol-relative или абсолютный)
+// относительно base и возвращает канонический URL.
+func (n *Normalizer) Resolve(base, ref string) (string, error) {
+	baseURL, err := url.Parse(strings.TrimSpace(base))
+	if err != nil {
+		return "", fmt.Errorf("невалидный базовый URL %q: %w", base, err)
+	}
+
+	refURL, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", fmt.Errorf("невалидный URL %q: %w", ref, err)
+	}
+
+	return n.normalize(baseURL.ResolveReference(refURL))
+}
+
+// Normalize приводит абсолютный URL к каноническому виду.
+func (n *Normalizer) Normalize(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("невалидный URL %q: %w", raw, err)
+	}
+
+	// ResolveReference с пустой ссылкой убирает "." и ".." из пути
+	return n.normalize(u.ResolveReference(&url.URL{}))
+}
+
+func (n *Normalizer) normalize(u *url.URL) (string, error) {
+	if !u.IsAbs() || u.Host == "" {
+		return "", fmt.Errorf("URL %q не абсолютный", u.String())
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = normalizeHost(u.Scheme, u.Host)
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.User = nil
+
+	if u.Path == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+
+	u.RawQuery = n.normalizeQuery(u.RawQuery)
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+func normalizeHost(scheme, host string) string {
+	host = strings.ToLower(host)
+
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return strings.TrimSuffix(host, ".")
+	}
+
+	hostname = strings.TrimSuffix(hostname, ".")
+	if port == "" || defaultPorts[scheme] == port {
+		if strings.Contains(hostname, ":") {
+			return "[" + hostname + "]"
+		}
+		return hostname
+	}
+
+	return net.JoinHostPort(hostname, port)
+}
+
+func (n *Normalizer) normalizeQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// Нестандартную строку запроса оставляем как есть, чтобы не потерять данные
+		return rawQuery
+	}
+
+	for key := range values {
+		if n.isTracking(key) {
+			delete(values, key)
+		}
+	}
+
+	// Encode сортирует параметры по ключу и сохраняет порядок значений одного ключа:
+	// для многих сайтов ?a=2&a=1 и ?a=1&a=2 - разные страницы
+	return values.Encode()
+}
+
+func (n *Normalizer) isTracking(key string) bool {
+	key = strings.ToLower(key)
+	if n.exact[key] {
+		return true
+	}
+	for _, prefix := range n.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
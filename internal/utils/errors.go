@@ -1,19 +1,135 @@
 package utils
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Category - класс ошибки, по которому воркер решает, повторять задачу или нет.
+type Category string
+
+const (
+	// CategoryTransient - сеть, таймауты, 5xx: повтор скорее всего поможет
+	CategoryTransient Category = "transient"
+	// CategoryBlocked - сайт отказал (403, капча): повтор с той же скоростью не поможет
+	CategoryBlocked Category = "blocked"
+	// CategoryParse - страница загружена, но не разобрана планом
+	CategoryParse Category = "parse"
+	// CategoryInvalidTask - задачу нельзя выполнить: битое сообщение, несуществующая страница
+	CategoryInvalidTask Category = "invalid_task"
+	// CategoryPlanNotFound - для задачи нет плана
+	CategoryPlanNotFound Category = "plan_not_found"
+	// CategoryStorage - не удалось записать в хранилище
+	CategoryStorage Category = "storage"
+	// CategoryUnknown - ошибка без категории
+	CategoryUnknown Category = "unknown"
+)
 
 type AppError struct {
-	Service string
-	Err     error
+	Service  string
+	Category Category
+	Err      error
+	// RetryAfter - не повторять раньше этого срока (заголовок Retry-After)
+	RetryAfter time.Duration
 }
 
 func (e *AppError) Error() string {
+	if e.Service == "" {
+		return e.Err.Error()
+	}
 	return fmt.Sprintf("[%s] %v", e.Service, e.Err)
 }
 
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
 func NewError(service string, err error) *AppError {
 	return &AppError{
 		Service: service,
 		Err:     err,
 	}
 }
+
+// WithCategory помечает err категорией; nil остаётся nil.
+func WithCategory(category Category, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &AppError{Category: category, Err: err}
+}
+
+func Transient(err error) error {
+	return WithCategory(CategoryTransient, err)
+}
+
+// RetryLater - временная ошибка, повтор которой сайт просит отложить на after.
+func RetryLater(err error, after time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &AppError{Category: CategoryTransient, Err: err, RetryAfter: after}
+}
+
+// RetryAfterOf возвращает срок из RetryLater или 0, если сайт его не назначил.
+func RetryAfterOf(err error) time.Duration {
+	for e := err; e != nil; {
+		var app *AppError
+		if !errors.As(e, &app) {
+			break
+		}
+		if app.RetryAfter > 0 {
+			return app.RetryAfter
+		}
+		e = app.Err
+	}
+	return 0
+}
+
+func Blocked(err error) error {
+	return WithCategory(CategoryBlocked, err)
+}
+
+func ParseFailure(err error) error {
+	return WithCategory(CategoryParse, err)
+}
+
+func InvalidTask(err error) error {
+	return WithCategory(CategoryInvalidTask, err)
+}
+
+func PlanNotFound(err error) error {
+	return WithCategory(CategoryPlanNotFound, err)
+}
+
+func StorageFailure(err error) error {
+	return WithCategory(CategoryStorage, err)
+}
+
+// CategoryOf возвращает ближайшую к вершине цепочки категорию. Без явной категории
+// таймауты и сетевые ошибки считаются временными.
+func CategoryOf(err error) Category {
+	if err == nil {
+		return ""
+	}
+
+	for e := err; e != nil; {
+		var app *AppError
+		if !errors.As(e, &app) {
+			break
+		}
+		if app.Category != "" {
+			return app.Category
+		}
+		e = app.Err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return CategoryTransient
+	}
+	return CategoryUnknown
+}
@@ -0,0 +1,60 @@
+package visited
+
+import (
+	"container/list"
+	"time"
+)
+
+type lruEntry struct {
+	key    string
+	seenAt time.Time
+}
+
+type lru struct {
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (c *lru) Get(key string) (time.Time, bool) {
+	el, ok := c.items[key]
+	if !ok {
+		return time.Time{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry).seenAt, true
+}
+
+func (c *lru) Remove(key string) {
+	if el, ok := c.items[key]; ok {
+		c.order.Remove(el)
+		delete(c.items, key)
+	}
+}
+
+func (c *lru) Add(key string, seenAt time.Time) {
+	if c.size <= 0 {
+		return
+	}
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruEntry).seenAt = seenAt
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, seenAt: seenAt})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
@@ -0,0 +1,148 @@
+package visited
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"go_parser/internal/database"
+	"go_parser/internal/urlnorm"
+	"sync"
+	"time"
+)
+
+type Entry struct {
+	database.BaseEntity `bson:",inline"`
+
+	Job    string    `bson:"job" json:"job"`
+	URL    string    `bson:"url" json:"url"`
+	SeenAt time.Time `bson:"seen_at" json:"seen_at"`
+}
+
+// Indexes - индексы коллекции visited. Запись ищется по _id, поэтому индекс нужен
+// только для TTL: при окне повторного посещения устаревшие записи удаляет MongoDB.
+func Indexes(revisit time.Duration) []database.Index {
+	if revisit <= 0 {
+		return nil
+	}
+	return []database.Index{
+		{Keys: []database.IndexKey{database.Asc("seen_at")}, TTL: revisit},
+	}
+}
+
+// Store - множество посещённых URL в рамках обхода.
+// Mongo хранит состояние между инстансами, LRU отсекает повторы без запроса в базу.
+type Store struct {
+	repo    database.Repository[*Entry]
+	norm    *urlnorm.Normalizer
+	revisit time.Duration
+
+	mu    sync.Mutex
+	cache *lru
+}
+
+// NewStore создаёт хранилище. revisit <= 0 означает, что URL повторно не посещается.
+func NewStore(repo database.Repository[*Entry], norm *urlnorm.Normalizer, cacheSize int, revisit time.Duration) *Store {
+	return &Store{
+		repo:    repo,
+		norm:    norm,
+		revisit: revisit,
+		cache:   newLRU(cacheSize),
+	}
+}
+
+// MarkIfNew отмечает URL посещённым и возвращает true, если в рамках задания job
+// он ещё не встречался или окно повторного посещения истекло.
+func (s *Store) MarkIfNew(ctx context.Context, job, rawURL string) (bool, error) {
+	canonical, err := s.norm.Normalize(rawURL)
+	if err != nil {
+		return false, err
+	}
+
+	key := entryKey(job, canonical)
+	now := time.Now()
+
+	s.mu.Lock()
+	seenAt, ok := s.cache.Get(key)
+	s.mu.Unlock()
+	if ok && s.fresh(seenAt, now) {
+		return false, nil
+	}
+
+	entry, err := s.repo.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	if entry != nil && s.fresh(entry.SeenAt, now) {
+		s.remember(key, entry.SeenAt)
+		return false, nil
+	}
+
+	if entry == nil {
+		entry = &Entry{
+			Job:    job,
+			URL:    canonical,
+			SeenAt: now,
+		}
+		entry.SetID(key)
+
+		if err := s.repo.Create(ctx, entry); err != nil {
+			if database.IsDuplicate(err) {
+				// Параллельный воркер успел отметить URL раньше
+				s.remember(key, now)
+				return false, nil
+			}
+			return false, err
+		}
+	} else {
+		// Окно истекло: повторное посещение достаётся тому, кто первым сдвинет seen_at
+		ok, err := s.repo.UpdateOne(ctx,
+			database.Filter{
+				"_id":     key,
+				"seen_at": map[string]interface{}{"lt": now.Add(-s.revisit)},
+			},
+			map[string]interface{}{"seen_at": now},
+		)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			s.remember(key, now)
+			return false, nil
+		}
+	}
+
+	s.remember(key, now)
+	return true, nil
+}
+
+// Forget снимает отметку с URL, задача для которого так и не попала в очередь,
+// чтобы ссылку можно было поставить снова.
+func (s *Store) Forget(ctx context.Context, job, rawURL string) error {
+	canonical, err := s.norm.Normalize(rawURL)
+	if err != nil {
+		return err
+	}
+	key := entryKey(job, canonical)
+
+	s.mu.Lock()
+	s.cache.Remove(key)
+	s.mu.Unlock()
+
+	return s.repo.Delete(ctx, key)
+}
+
+func (s *Store) fresh(seenAt, now time.Time) bool {
+	return s.revisit <= 0 || now.Sub(seenAt) < s.revisit
+}
+
+func (s *Store) remember(key string, seenAt time.Time) {
+	s.mu.Lock()
+	s.cache.Add(key, seenAt)
+	s.mu.Unlock()
+}
+
+func entryKey(job, canonical string) string {
+	sum := sha1.Sum([]byte(job + "\n" + canonical))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,124 @@
+package worker
+
+import (
+	"fmt"
+	"go_parser/internal/domain/queue"
+	"go_parser/internal/utils"
+	"runtime/metrics"
+	"time"
+)
+
+// limitPollInterval - как часто проверять лимиты, пока приём сообщений приостановлен
+const limitPollInterval = 500 * time.Millisecond
+
+// Limit сообщает о нехватке ресурса. Пока превышен хоть один лимит,
+// пул не забирает новые сообщения из очереди.
+type Limit interface {
+	Exceeded() (reason string, exceeded bool)
+}
+
+// heapMetric - объём живых и ещё не собранных объектов кучи, аналог MemStats.HeapAlloc.
+// runtime/metrics читается без остановки мира, в отличие от runtime.ReadMemStats,
+// поэтому лимит можно проверять на каждом сообщении.
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+type memoryLimit struct {
+	maxBytes uint64
+}
+
+// NewMemoryLimit срабатывает, когда занятая куча превышает maxBytes.
+func NewMemoryLimit(maxBytes uint64) Limit {
+	return &memoryLimit{maxBytes: maxBytes}
+}
+
+func (l *memoryLimit) Exceeded() (string, bool) {
+	sample := []metrics.Sample{{Name: heapMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return "", false
+	}
+
+	heap := sample[0].Value.Uint64()
+	if heap < l.maxBytes {
+		return "", false
+	}
+	return fmt.Sprintf("память: %d MB из %d MB", heap>>20, l.maxBytes>>20), true
+}
+
+type BrowserAvailability interface {
+	Available() int
+}
+
+type browserLimit struct {
+	pool BrowserAvailability
+}
+
+// NewBrowserLimit срабатывает, когда в пуле не осталось свободных браузеров.
+// Лимит проверяется до разбора сообщения, поэтому задерживает и задачи, которым
+// браузер не нужен.
+func NewBrowserLimit(pool BrowserAvailability) Limit {
+	return &browserLimit{pool: pool}
+}
+
+func (l *browserLimit) Exceeded() (string, bool) {
+	if l.pool.Available() > 0 {
+		return "", false
+	}
+	return "нет свободных браузеров", true
+}
+
+// Consume пересылает сообщения из очереди воркерам. Буфер Msg ограничен, а prefetch брокера
+// не даёт ему прислать больше, поэтому пока лимиты превышены, новые сообщения ждут в очереди.
+// После остановки пула сообщения возвращаются брокеру, пока он не закроет msgs.
+func (w *WorkerPool) Consume(msgs <-chan queue.WrapperMessage) {
+	defer close(w.consumed)
+
+	for msg := range msgs {
+		if w.stopping() || !w.waitLimits() {
+			w.requeue(msg)
+			continue
+		}
+
+		utils.Logger.Printf("Получено новое сообщение: %s\n", msg.GetBody())
+
+		select {
+		case w.Msg <- msg:
+		case <-w.quit:
+			w.requeue(msg)
+		}
+	}
+}
+
+// waitLimits блокируется, пока превышен какой-либо лимит. false - пул остановлен.
+func (w *WorkerPool) waitLimits() bool {
+	paused := false
+	for {
+		reason, exceeded := w.exceeded()
+		if !exceeded {
+			if paused {
+				utils.Logger.Println("Приём сообщений возобновлён")
+			}
+			return true
+		}
+
+		if !paused {
+			utils.Logger.Printf("Приём сообщений приостановлен: %s\n", reason)
+			paused = true
+		}
+
+		select {
+		case <-time.After(limitPollInterval):
+		case <-w.quit:
+			return false
+		}
+	}
+}
+
+func (w *WorkerPool) exceeded() (string, bool) {
+	for _, l := range w.limits {
+		if reason, exceeded := l.Exceeded(); exceeded {
+			return reason, true
+		}
+	}
+	return "", false
+}
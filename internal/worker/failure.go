@@ -0,0 +1,64 @@
+package worker
+
+import (
+	"context"
+	"go_parser/internal/domain/queue"
+	"go_parser/internal/domain/task"
+	"go_parser/internal/utils"
+)
+
+// action - что сделать с сообщением упавшей задачи
+type action int
+
+const (
+	// actionRetry - повтор с задержкой, после исчерпания попыток - dead-letter
+	actionRetry action = iota
+	// actionDeadLetter - сразу в dead-letter: повтор не поможет, но задачу стоит разобрать
+	actionDeadLetter
+	// actionReject - не повторять: задачу выполнить нельзя, сообщение сохраняется в dead-letter
+	actionReject
+)
+
+var categoryActions = map[utils.Category]action{
+	utils.CategoryTransient:    actionRetry,
+	utils.CategoryStorage:      actionRetry,
+	utils.CategoryBlocked:      actionDeadLetter,
+	utils.CategoryParse:        actionDeadLetter,
+	utils.CategoryInvalidTask:  actionReject,
+	utils.CategoryPlanNotFound: actionReject,
+}
+
+// actionFor - решение по категории ошибки; ошибки без категории повторяются.
+func actionFor(err error) action {
+	if a, ok := categoryActions[utils.CategoryOf(err)]; ok {
+		return a
+	}
+	return actionRetry
+}
+
+// fail повторяет задачу или переносит её в dead-letter в зависимости от категории ошибки.
+func (w *WorkerPool) fail(ctx context.Context, msg queue.WrapperMessage, task *task.Task, cause error) {
+	switch actionFor(cause) {
+	case actionReject:
+		utils.Logger.Printf("[SKIP] %s (%s): %v\n", task.URL, utils.CategoryOf(cause), cause)
+		// Reject удалил бы сообщение: у основной очереди нет dead-letter обмена
+		if err := w.h.DeadLetter(ctx, task, cause); err != nil {
+			utils.Logger.Printf("Ошибка отправки задачи в dead-letter очередь: %v\n", err)
+			msg.TryAgain()
+			return
+		}
+		w.track(w.tracker.Rejected(ctx, task, cause))
+		msg.Success()
+	case actionDeadLetter:
+		if err := w.h.DeadLetter(ctx, task, cause); err != nil {
+			utils.Logger.Printf("Ошибка отправки задачи в dead-letter очередь: %v\n", err)
+			w.track(w.tracker.Failed(ctx, task, cause))
+			msg.TryAgain()
+			return
+		}
+		w.track(w.tracker.Failed(ctx, task, cause))
+		msg.Success()
+	default:
+		w.retry(ctx, msg, task, cause)
+	}
+}
@@ -1,91 +1,392 @@
-package worker
-
-import (
-	"encoding/json"
-	"go_parser/internal/domain/plan"
-	"go_parser/internal/domain/queue"
-	"go_parser/internal/domain/task"
-	"go_parser/internal/utils"
-)
-
-type PlanRegister interface {
-	Get(name string) (plan.Plan, error)
-}
-
-type Handler interface {
-	HandleResult(result *plan.PlanResult, foundURLs []plan.FoundURL, err error) error
-}
-
-type WorkerPool struct {
-	Msg     chan queue.WrapperMessage
-	quit    chan struct{}
-	planReg PlanRegister
-	count   int
-	h       Handler
-}
-
-func NewWorkerPool(count int, planReg PlanRegister, h Handler) *WorkerPool {
-	return &WorkerPool{
-		Msg:     make(chan queue.WrapperMessage),
-		quit:    make(chan struct{}),
-		planReg: planReg,
-		count:   count,
-		h:       h,
-	}
-}
-
-func (w WorkerPool) Start() {
-	for i := 0; i < w.count; i++ {
-		go func() {
-			for {
-				select {
-				case msg := <-w.Msg:
-					w.proccesTask(msg)
-				case <-w.quit:
-					return
-				}
-			}
-		}()
-	}
-}
-
-func (w *WorkerPool) proccesTask(msg queue.WrapperMessage) {
-	var task *task.Task
-
-	if err := json.Unmarshal(msg.GetBody(), &task); err != nil {
-		utils.Logger.Printf("Ошибка разбора сообщения: %v\n", err)
-		res := &plan.PlanResult{
-			URL:      task.URL,
-			PlanName: task.Plan,
-			Error:    err.Error(),
-		}
-		var urls []plan.FoundURL
-		w.h.HandleResult(res, urls, err)
-		msg.Reject()
-	}
-
-	pln, err := w.planReg.Get(task.Plan)
-
-	if err != nil {
-		utils.Logger.Printf("Ошибка разбора сообщения: %v\n", err)
-		res := &plan.PlanResult{
-			URL:      task.URL,
-			PlanName: task.Plan,
-			Error:    err.Error(),
-		}
-		var urls []plan.FoundURL
-		w.h.HandleResult(res, urls, err)
-		msg.Reject()
-	}
-
-	res, urls, err := pln.Execute(task)
-
-	err = w.h.HandleResult(res, urls, err)
-	if err != nil {
-		utils.Logger.Printf("Обработка результата выполнена успешно")
-	}
-}
-
-func (w *WorkerPool) Stop() {
-	close(w.quit)
-}
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_parser/internal/domain/job"
+	"go_parser/internal/domain/plan"
+	"go_parser/internal/domain/queue"
+	"go_parser/internal/domain/task"
+	"go_parser/internal/utils"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type PlanRegister interface {
+	Get(name string) (plan.Plan, error)
+	Resolve(url string) (plan.Plan, error)
+}
+
+type Handler interface {
+	HandleResult(ctx context.Context, result *plan.PlanResult, foundURLs []plan.FoundURL, err error) error
+	Retry(ctx context.Context, t *task.Task, cause error) (deadLettered bool, err error)
+	DeadLetter(ctx context.Context, t *task.Task, cause error) error
+	DeadLetterRaw(ctx context.Context, body []byte, cause error) error
+	Park(ctx context.Context, jobID string, tasks []*task.Task)
+}
+
+type TaskTracker interface {
+	Running(ctx context.Context, t *task.Task) error
+	Retrying(ctx context.Context, t *task.Task, reason error) error
+	Succeeded(ctx context.Context, t *task.Task) error
+	Failed(ctx context.Context, t *task.Task, reason error) error
+	Rejected(ctx context.Context, t *task.Task, reason error) error
+	Cancelled(ctx context.Context, t *task.Task) error
+	Requeued(ctx context.Context, t *task.Task) error
+}
+
+type JobState interface {
+	Get(ctx context.Context, id string) (*job.Job, error)
+}
+
+// Politeness выдерживает robots.txt и ограничения хоста перед выполнением плана
+type Politeness interface {
+	Acquire(ctx context.Context, rawURL string) (release func(), err error)
+}
+
+// ErrTaskTimeout - задача не уложилась в свой дедлайн; такая ошибка повторяется
+var ErrTaskTimeout = errors.New("превышено время выполнения задачи")
+
+// requeueTrackTimeout - сколько ждать записи статуса задачи, прерванной остановкой пула
+const requeueTrackTimeout = 5 * time.Second
+
+// resultSaveTimeout - сколько даётся на запись результата и публикацию найденных ссылок.
+// Дедлайн задачи к этому моменту может почти истечь, поэтому у записи он свой
+const resultSaveTimeout = 30 * time.Second
+
+// consumeCloseGrace - сколько при остановке ждать закрытия подписки, если время уже вышло
+const consumeCloseGrace = 2 * time.Second
+
+type WorkerPool struct {
+	Msg  chan queue.WrapperMessage
+	quit chan struct{}
+	// ctx отменяется в Stop и прерывает выполняющиеся задачи
+	ctx      context.Context
+	cancel   context.CancelFunc
+	timeout  time.Duration
+	planReg  PlanRegister
+	count    int
+	h        Handler
+	tracker  TaskTracker
+	jobs     JobState
+	polite   Politeness
+	browsers io.Closer
+	limits   []Limit
+
+	workers sync.WaitGroup
+	// consumed закрывается, когда Consume вернёт брокеру все сообщения после остановки
+	consumed chan struct{}
+	// Счётчики для отчёта об остановке
+	running     atomic.Int64
+	interrupted atomic.Int64
+	requeued    atomic.Int64
+}
+
+// buffer - сколько сообщений может ждать свободного воркера,
+// timeout - таймаут задачи, если его не задают опции задачи или план
+func NewWorkerPool(
+	count int,
+	buffer int,
+	timeout time.Duration,
+	planReg PlanRegister,
+	h Handler,
+	tracker TaskTracker,
+	jobs JobState,
+	polite Politeness,
+	browsers io.Closer,
+	limits ...Limit,
+) *WorkerPool {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WorkerPool{
+		Msg:      make(chan queue.WrapperMessage, buffer),
+		quit:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+		timeout:  timeout,
+		planReg:  planReg,
+		count:    count,
+		h:        h,
+		tracker:  tracker,
+		jobs:     jobs,
+		polite:   polite,
+		browsers: browsers,
+		limits:   limits,
+		consumed: make(chan struct{}),
+	}
+}
+
+func (w *WorkerPool) Start() {
+	for i := 0; i < w.count; i++ {
+		w.workers.Add(1)
+		go func() {
+			defer w.workers.Done()
+			for {
+				select {
+				case msg := <-w.Msg:
+					if w.stopping() {
+						// select выбирает случайно: сообщение могло прийти вместе с остановкой
+						w.requeue(msg)
+						return
+					}
+					w.running.Add(1)
+					w.proccesTask(msg)
+					w.running.Add(-1)
+				case <-w.quit:
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (w *WorkerPool) proccesTask(msg queue.WrapperMessage) {
+	var task *task.Task
+	ctx := w.ctx
+
+	if err := json.Unmarshal(msg.GetBody(), &task); err != nil || task == nil {
+		if err == nil {
+			err = errors.New("пустая задача")
+		}
+		utils.Logger.Printf("Ошибка разбора сообщения (%s): %v\n", utils.CategoryInvalidTask, err)
+		// Reject без dead-letter обмена удаляет сообщение, поэтому оно публикуется в DLQ явно
+		if dlqErr := w.h.DeadLetterRaw(ctx, msg.GetBody(), err); dlqErr != nil {
+			utils.Logger.Printf("Ошибка отправки сообщения в dead-letter очередь: %v\n", dlqErr)
+			msg.TryAgain()
+			return
+		}
+		msg.Success()
+		return
+	}
+
+	if !w.jobActive(ctx, task) {
+		msg.Success()
+		return
+	}
+
+	pln, err := w.resolvePlan(task)
+
+	if err != nil {
+		res := &plan.PlanResult{
+			URL:      task.URL,
+			PlanName: task.Plan,
+			Error:    err.Error(),
+		}
+		var urls []plan.FoundURL
+		w.h.HandleResult(ctx, res, urls, err)
+		w.fail(ctx, msg, task, err)
+		return
+	}
+
+	// Запрет robots.txt отбрасывает задачу, недоступный robots.txt - повторяет
+	release, err := w.polite.Acquire(ctx, task.URL)
+	if err != nil && ctx.Err() != nil {
+		w.interrupt(msg, task)
+		return
+	}
+	if err != nil {
+		w.fail(ctx, msg, task, err)
+		return
+	}
+
+	w.track(w.tracker.Running(ctx, task))
+
+	timeout := w.taskTimeout(task, pln)
+	taskCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	res, urls, err := pln.Execute(taskCtx, task)
+	release()
+	if ctx.Err() != nil {
+		w.interrupt(msg, task)
+		return
+	}
+	if err != nil && (errors.Is(err, context.DeadlineExceeded) || taskCtx.Err() != nil) {
+		err = utils.Transient(fmt.Errorf("%w (%s): %v", ErrTaskTimeout, timeout, err))
+	}
+	if res == nil {
+		res = &plan.PlanResult{
+			URL:      task.URL,
+			PlanName: task.Plan,
+			Depth:    task.Depth,
+		}
+	}
+	res.TaskID = task.GetID()
+	res.JobID = task.JobID
+	res.MaxDepth = task.MaxDepth
+
+	saveCtx, cancelSave := context.WithTimeout(context.WithoutCancel(ctx), resultSaveTimeout)
+	defer cancelSave()
+	err = w.h.HandleResult(saveCtx, res, urls, err)
+	if err != nil {
+		w.fail(ctx, msg, task, err)
+		return
+	}
+
+	w.track(w.tracker.Succeeded(ctx, task))
+	msg.Success()
+	utils.Logger.Printf("Обработка результата выполнена успешно")
+}
+
+// jobActive откладывает задачу приостановленного задания и снимает задачу отменённого.
+// При ошибке чтения задания задача выполняется.
+func (w *WorkerPool) jobActive(ctx context.Context, tk *task.Task) bool {
+	jb, err := w.jobs.Get(ctx, tk.JobID)
+	if err != nil {
+		utils.Logger.Printf("Ошибка чтения задания %s: %v\n", tk.JobID, err)
+		return true
+	}
+	if jb == nil {
+		return true
+	}
+
+	switch jb.Status {
+	case job.StatusPaused:
+		w.h.Park(ctx, tk.JobID, []*task.Task{tk})
+		return false
+	case job.StatusCancelled:
+		w.track(w.tracker.Cancelled(ctx, tk))
+		return false
+	default:
+		return true
+	}
+}
+
+// taskTimeout выбирает таймаут задачи: опции задачи, затем план, затем общий.
+func (w *WorkerPool) taskTimeout(task *task.Task, pln plan.Plan) time.Duration {
+	timeout, err := plan.TimeoutOption(task.Options)
+	if err != nil {
+		utils.Logger.Printf("Задача %s: %v, используется таймаут по умолчанию\n", task.URL, err)
+	}
+	if timeout > 0 {
+		return timeout
+	}
+	if tp, ok := pln.(plan.Timeouter); ok && tp.Timeout() > 0 {
+		return tp.Timeout()
+	}
+	return w.timeout
+}
+
+func (w *WorkerPool) resolvePlan(task *task.Task) (plan.Plan, error) {
+	if !plan.IsAuto(task.Plan) {
+		return w.planReg.Get(task.Plan)
+	}
+
+	pln, err := w.planReg.Resolve(task.URL)
+	if err != nil {
+		return nil, err
+	}
+	task.Plan = pln.Name()
+	return pln, nil
+}
+
+func (w *WorkerPool) retry(ctx context.Context, msg queue.WrapperMessage, task *task.Task, cause error) {
+	deadLettered, err := w.h.Retry(ctx, task, cause)
+	if err != nil {
+		// Не удалось переотправить - возвращаем сообщение брокеру как есть
+		utils.Logger.Printf("Ошибка повторной отправки задачи: %v\n", err)
+		w.track(w.tracker.Failed(ctx, task, cause))
+		msg.TryAgain()
+		return
+	}
+
+	if deadLettered {
+		w.track(w.tracker.Failed(ctx, task, cause))
+	} else {
+		w.track(w.tracker.Retrying(ctx, task, cause))
+	}
+	msg.Success()
+}
+
+// interrupt возвращает брокеру задачу, прерванную остановкой пула.
+func (w *WorkerPool) interrupt(msg queue.WrapperMessage, task *task.Task) {
+	utils.Logger.Printf("Задача %s прервана остановкой пула\n", task.URL)
+	w.interrupted.Add(1)
+
+	// ctx пула уже отменён, а статус нужно вернуть, чтобы счётчики задания сошлись
+	ctx, cancel := context.WithTimeout(context.Background(), requeueTrackTimeout)
+	defer cancel()
+	w.track(w.tracker.Requeued(ctx, task))
+	msg.TryAgain()
+}
+
+// requeue возвращает брокеру сообщение, которое пул не успел взять в работу.
+func (w *WorkerPool) requeue(msg queue.WrapperMessage) {
+	w.requeued.Add(1)
+	msg.TryAgain()
+}
+
+func (w *WorkerPool) stopping() bool {
+	select {
+	case <-w.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *WorkerPool) track(err error) {
+	if err != nil {
+		utils.Logger.Printf("Ошибка обновления статуса задачи: %v\n", err)
+	}
+}
+
+// Shutdown останавливает пул. Новые сообщения возвращаются брокеру, выполняющиеся задачи
+// дорабатывают и подтверждаются. Если ctx истёк раньше, оставшиеся задачи прерываются
+// и тоже возвращаются в очередь. Брокер должен перестать выдавать сообщения до вызова,
+// а закрываться - после: иначе не пройдут ack и nack.
+func (w *WorkerPool) Shutdown(ctx context.Context) {
+	close(w.quit)
+	inFlight := w.running.Load()
+	utils.Logger.Printf("Остановка пула воркеров: задач в работе %d\n", inFlight)
+
+	done := make(chan struct{})
+	go func() {
+		w.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		utils.Logger.Printf("Время остановки истекло, прерываются задачи: %d\n", w.running.Load())
+		w.cancel()
+		<-done
+	}
+	w.cancel()
+
+	// Подписка закрывается вскоре после StopConsuming; даже если время вышло, даём ей
+	// вернуть полученные сообщения, иначе они останутся неподтверждёнными до закрытия брокера
+	grace := consumeCloseGrace
+	if deadline, ok := ctx.Deadline(); ok {
+		grace = max(time.Until(deadline), consumeCloseGrace)
+	}
+	select {
+	case <-w.consumed:
+	case <-time.After(grace):
+		utils.Logger.Println("Не дождались закрытия подписки на очередь")
+	}
+
+	// Сообщения, ждавшие свободного воркера
+	for pending := true; pending; {
+		select {
+		case msg := <-w.Msg:
+			w.requeue(msg)
+		default:
+			pending = false
+		}
+	}
+
+	interrupted := w.interrupted.Load()
+	utils.Logger.Printf("Пул воркеров остановлен: завершено задач %d, прервано %d, возвращено в очередь без выполнения %d\n",
+		max(inFlight-interrupted, 0), interrupted, w.requeued.Load())
+
+	if w.browsers != nil {
+		if err := w.browsers.Close(); err != nil {
+			utils.Logger.Printf("Ошибка закрытия пула браузеров: %v\n", err)
+		}
+	}
+}
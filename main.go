@@ -2,21 +2,41 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	"go_parser/internal/api"
+	"go_parser/internal/changes"
 	"go_parser/internal/config"
 	"go_parser/internal/database"
+	"go_parser/internal/domain/fetcher"
+	"go_parser/internal/domain/job"
+	"go_parser/internal/domain/plan"
+	domainqueue "go_parser/internal/domain/queue"
 	"go_parser/internal/domain/record"
+	"go_parser/internal/domain/schedule"
+	"go_parser/internal/domain/task"
 	"go_parser/internal/handler"
+	"go_parser/internal/jobs"
 	"go_parser/internal/parser/plans"
+	"go_parser/internal/politeness"
 	"go_parser/internal/queue"
+	"go_parser/internal/records"
+	"go_parser/internal/scheduler"
+	"go_parser/internal/services"
+	"go_parser/internal/tracker"
+	"go_parser/internal/urlnorm"
 	"go_parser/internal/utils"
+	"go_parser/internal/visited"
 	"go_parser/internal/worker"
 
 	"github.com/playwright-community/playwright-go"
+	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 func main() {
@@ -29,6 +49,12 @@ func main() {
 
 	ctx := context.Background()
 
+	trackingParams := cfg.TrackingParams
+	if trackingParams == nil {
+		trackingParams = urlnorm.DefaultTrackingParams
+	}
+	norm := urlnorm.New(trackingParams)
+
 	recordRepo := database.NewMongoRepository[*record.Record](
 		cfg.MongoURI,
 		"parser_db",
@@ -42,59 +68,148 @@ func main() {
 
 	defer recordRepo.Close(ctx)
 
-	utils.Logger.Println("Подключение к RabbitMQ...")
-	rabbitMQConn, err := queue.ConnectToRabbitMQ(cfg.RabbitMQURI)
-	if err != nil {
-		utils.Logger.Fatalf("Ошибка подключения к RabbitMQ: %v %s", err, cfg.RabbitMQURI)
+	historyRepo := database.NewMongoRepository[*record.History](
+		cfg.MongoURI,
+		"parser_db",
+		"record_history",
+	)
+
+	if err := historyRepo.Connect(ctx); err != nil {
+		utils.Logger.Fatalf("Ошибка подключения к MongoDB: %v %s", err, cfg.MongoURI)
 	}
-	defer func() {
-		if err := rabbitMQConn.Close(); err != nil {
-			utils.Logger.Printf("Ошибка закрытия соединения с RabbitMQ: %v", err)
-		} else {
-			utils.Logger.Println("Успешно закрыто соединение с RabbitMQ.")
-		}
-	}()
-	utils.Logger.Println("Успешно подключено к RabbitMQ.")
 
-	utils.Logger.Println("Создание канала RabbitMQ...")
-	ch, err := rabbitMQConn.Channel()
-	if err != nil {
-		utils.Logger.Fatalf("Ошибка создания канала RabbitMQ: %v", err)
+	defer historyRepo.Close(ctx)
+
+	changeRepo := database.NewMongoRepository[*changes.Event](
+		cfg.MongoURI,
+		"parser_db",
+		"record_changes",
+	)
+
+	if err := changeRepo.Connect(ctx); err != nil {
+		utils.Logger.Fatalf("Ошибка подключения к MongoDB: %v %s", err, cfg.MongoURI)
 	}
-	defer func() {
-		if err := ch.Close(); err != nil {
-			utils.Logger.Printf("Ошибка закрытия канала RabbitMQ: %v", err)
-		} else {
-			utils.Logger.Println("Успешно закрыт канал RabbitMQ.")
-		}
-	}()
-	utils.Logger.Println("Канал RabbitMQ успешно создан.")
-
-	utils.Logger.Println("Объявление очереди...")
-	q, err := ch.QueueDeclare(
-		cfg.QueueName, // Имя очереди
-		false,         // durable (не сохранять на диск)
-		false,         // autoDelete (не удалять при отсутствии потребителей)
-		false,         // exclusive (очередь доступна для других соединений)
-		false,         // noWait (ждать ответа от сервера)
-		nil,           // arguments (дополнительные аргументы)
+
+	defer changeRepo.Close(ctx)
+
+	taskRepo := database.NewMongoRepository[*task.Task](
+		cfg.MongoURI,
+		"parser_db",
+		"tasks",
+	)
+
+	if err := taskRepo.Connect(ctx); err != nil {
+		utils.Logger.Fatalf("Ошибка подключения к MongoDB: %v %s", err, cfg.MongoURI)
+	}
+
+	defer taskRepo.Close(ctx)
+
+	jobRepo := database.NewMongoRepository[*job.Job](
+		cfg.MongoURI,
+		"parser_db",
+		"jobs",
+	)
+
+	if err := jobRepo.Connect(ctx); err != nil {
+		utils.Logger.Fatalf("Ошибка подключения к MongoDB: %v %s", err, cfg.MongoURI)
+	}
+
+	defer jobRepo.Close(ctx)
+
+	jobManager := jobs.NewManager(jobRepo, taskRepo)
+
+	scheduleRepo := database.NewMongoRepository[*schedule.Schedule](
+		cfg.MongoURI,
+		"parser_db",
+		"schedules",
+	)
+
+	if err := scheduleRepo.Connect(ctx); err != nil {
+		utils.Logger.Fatalf("Ошибка подключения к MongoDB: %v %s", err, cfg.MongoURI)
+	}
+
+	defer scheduleRepo.Close(ctx)
+	taskTracker := tracker.NewTracker(taskRepo, jobManager)
+
+	visitedRepo := database.NewMongoRepository[*visited.Entry](
+		cfg.MongoURI,
+		"parser_db",
+		"visited",
 	)
+
+	if err := visitedRepo.Connect(ctx); err != nil {
+		utils.Logger.Fatalf("Ошибка подключения к MongoDB: %v %s", err, cfg.MongoURI)
+	}
+
+	defer visitedRepo.Close(ctx)
+
+	// Записи, задачи и посещённые URL ранних версий хранили ID во вложенном baseentity
+	migrateEmbeddedIDs(ctx, "records", recordRepo)
+	migrateEmbeddedIDs(ctx, "tasks", taskRepo)
+	migrateEmbeddedIDs(ctx, "visited", visitedRepo)
+
+	syncIndexes(ctx, cfg.MongoIndexMode, "records", recordRepo, record.Indexes)
+	syncIndexes(ctx, cfg.MongoIndexMode, "record_history", historyRepo, record.HistoryIndexes)
+	syncIndexes(ctx, cfg.MongoIndexMode, "record_changes", changeRepo, changes.EventIndexes)
+	syncIndexes(ctx, cfg.MongoIndexMode, "tasks", taskRepo, task.Indexes)
+	syncIndexes(ctx, cfg.MongoIndexMode, "jobs", jobRepo, job.Indexes)
+	syncIndexes(ctx, cfg.MongoIndexMode, "schedules", scheduleRepo, schedule.Indexes)
+	syncIndexes(ctx, cfg.MongoIndexMode, "visited", visitedRepo, visited.Indexes(cfg.VisitedRevisitWindow))
+
+	visitedStore := visited.NewStore(visitedRepo, norm, cfg.VisitedCacheSize, cfg.VisitedRevisitWindow)
+
+	retryPolicy := queue.NewRetryPolicy(cfg.RetryMaxAttempts, cfg.RetryBaseDelay, cfg.RetryMaxDelay)
+
+	strategy, err := handler.NewCrawlStrategy(cfg.CrawlStrategy, cfg.QueueMaxPriority)
 	if err != nil {
-		utils.Logger.Fatalf("Ошибка объявления очереди: %v", err)
+		utils.Logger.Fatalf("Ошибка настройки CRAWL_STRATEGY: %v", err)
+	}
+
+	var broker domainqueue.Broker
+	if cfg.QueueBackend == "memory" {
+		broker = queue.NewMemoryBroker(cfg.MemoryQueueSize)
+		utils.Logger.Println("Используется очередь в памяти процесса.")
+	} else {
+		topology := func(ch *amqp.Channel) error {
+			_, err := ch.QueueDeclare(
+				cfg.QueueName, // Имя очереди
+				false,         // durable (не сохранять на диск)
+				false,         // autoDelete (не удалять при отсутствии потребителей)
+				false,         // exclusive (очередь доступна для других соединений)
+				false,         // noWait (ждать ответа от сервера)
+				amqp.Table{ // arguments: приоритет сообщений 0..x-max-priority
+					"x-max-priority": int32(strategy.MaxPriority),
+				},
+			)
+			if err != nil {
+				return fmt.Errorf("ошибка объявления очереди: %w", err)
+			}
+			if cfg.ChangesExchange != "" {
+				// Подписчики сами привязывают свои очереди по имени плана
+				err := ch.ExchangeDeclare(cfg.ChangesExchange, amqp.ExchangeTopic, true, false, false, false, nil)
+				if err != nil {
+					return fmt.Errorf("ошибка объявления exchange изменений: %w", err)
+				}
+			}
+			return queue.DeclareRetryTopology(ch, cfg.QueueName, retryPolicy)
+		}
+
+		utils.Logger.Println("Подключение к RabbitMQ...")
+		rabbit, err := queue.NewRabbitBroker(cfg.RabbitMQURI, retryPolicy, cfg.WorkerCount+cfg.WorkerBuffer, topology)
+		if err != nil {
+			utils.Logger.Fatalf("Ошибка подключения к RabbitMQ: %v %s", err, cfg.RabbitMQURI)
+		}
+		utils.Logger.Printf("Успешно подключено к RabbitMQ, очередь '%s' и очереди повторов объявлены.\n", cfg.QueueName)
+
+		broker = rabbit
 	}
-	utils.Logger.Printf("Очередь '%s' успешно объявлена.\n", q.Name)
+
+	detector := changes.NewDetector(changeRepo, broker, cfg.ChangesExchange, cfg.DiffListKeys)
+	recordStore := records.NewStore(recordRepo, historyRepo, detector)
 
 	// Подписка на очередь
 	utils.Logger.Println("Подписка на очередь...")
-	msgs, err := ch.Consume(
-		q.Name, // Имя очереди
-		"",     // consumer tag (пустое значение для автоматической генерации)
-		false,  // autoAck (не подтверждать сообщения автоматически)
-		false,  // exclusive (очередь доступна для других потребителей)
-		false,  // noLocal (доставлять сообщения, отправленные тем же соединением)
-		false,  // noWait (ждать ответа от сервера)
-		nil,    // arguments (дополнительные аргументы)
-	)
+	msgs, err := broker.Consume(cfg.QueueName)
 	if err != nil {
 		utils.Logger.Fatalf("Ошибка подписки на очередь: %v", err)
 	}
@@ -105,22 +220,188 @@ func main() {
 
 	utils.Logger.Println("Ожидание сообщений. Для выхода нажмите CTRL+C.")
 
-	h := handler.NewHandler(recordRepo, ch, cfg.QueueName)
+	browsers, err := services.NewBrowserPool(cfg.BrowserPoolSize, cfg.BrowserMaxPages)
+	if err != nil {
+		utils.Logger.Fatalf("Ошибка запуска пула браузеров: %v", err)
+	}
+	utils.Logger.Printf("Пул браузеров запущен: %d шт.", cfg.BrowserPoolSize)
+
+	httpFetcher := services.NewHTTPFetcher(cfg.FetchTimeout, cfg.UserAgent)
+	fetchers := fetcher.NewRegistry(
+		httpFetcher,
+		services.NewBrowserFetcher(browsers),
+	)
+
+	var limiter politeness.Limiter
+	if cfg.PolitenessStore == "memory" {
+		limiter = politeness.NewLocalLimiter(cfg.PolitenessMaxConcurrent)
+	} else {
+		leaseRepo := database.NewMongoRepository[*politeness.Lease](
+			cfg.MongoURI,
+			"parser_db",
+			"politeness",
+		)
 
+		if err := leaseRepo.Connect(ctx); err != nil {
+			utils.Logger.Fatalf("Ошибка подключения к MongoDB: %v %s", err, cfg.MongoURI)
+		}
+
+		defer leaseRepo.Close(ctx)
+
+		syncIndexes(ctx, cfg.MongoIndexMode, "politeness", leaseRepo, politeness.LeaseIndexes)
+
+		limiter = politeness.NewMongoLimiter(leaseRepo, cfg.PolitenessMaxConcurrent, cfg.PolitenessLeaseTTL)
+	}
+	robots := politeness.NewRobotsCache(httpFetcher, cfg.RobotsCacheTTL)
+	gate := politeness.NewGate(robots, limiter, cfg.RobotsUserAgent, cfg.PolitenessMinInterval)
+
+	// Встроенные планы регистрируются первыми: декларативный план с тем же именем
+	// получит ошибку регистрации, а не займёт место встроенного
 	pr := plans.NewRegistr()
-	pr.Register(plans.NewHackerNewsPlan())
+	for _, p := range []plan.Plan{
+		plans.NewHackerNewsPlan(fetchers, norm),
+		plans.NewGenericPlan(fetchers, norm),
+	} {
+		if err := pr.Register(p); err != nil {
+			utils.Logger.Fatalf("Ошибка регистрации плана: %v", err)
+		}
+	}
+
+	declarative, err := plans.LoadDeclarativePlans(cfg.PlansDir, fetchers, norm)
+	if err != nil {
+		utils.Logger.Fatalf("Ошибка загрузки планов: %v", err)
+	}
+	for _, p := range declarative {
+		if err := pr.Register(p); err != nil {
+			utils.Logger.Fatalf("Ошибка регистрации плана: %v", err)
+		}
+		utils.Logger.Printf("Загружен план '%s' из %s", p.Name(), cfg.PlansDir)
+	}
+
+	if err := pr.SetFallback(cfg.FallbackPlan); err != nil {
+		utils.Logger.Fatalf("Ошибка настройки fallback-плана: %v", err)
+	}
+
+	if err := handler.CheckLinkPolicy(cfg.UnmatchedLinks); err != nil {
+		utils.Logger.Fatalf("Ошибка настройки UNMATCHED_LINKS: %v", err)
+	}
+
+	h := handler.NewHandler(
+		recordStore,
+		taskTracker,
+		jobManager,
+		visitedStore,
+		norm,
+		pr,
+		cfg.UnmatchedLinks,
+		broker,
+		cfg.QueueName,
+		retryPolicy,
+		strategy,
+	)
+
+	// Брокер отдаёт не больше сообщений, чем воркеры и буфер могут принять
+	var limits []worker.Limit
+	if cfg.MemoryLimitMB > 0 {
+		limits = append(limits, worker.NewMemoryLimit(uint64(cfg.MemoryLimitMB)<<20))
+	}
+	if cfg.PauseOnBrowserPool {
+		limits = append(limits, worker.NewBrowserLimit(browsers))
+	}
 
-	wp := worker.NewWorkerPool(3, pr, h)
+	wp := worker.NewWorkerPool(cfg.WorkerCount, cfg.WorkerBuffer, cfg.TaskTimeout, pr, h, taskTracker, jobManager, gate, browsers, limits...)
 
 	wp.Start()
 
-	go func() {
-		for msg := range msgs {
-			utils.Logger.Printf("Получено новое сообщение: %s\n", msg.Body)
-			wp.Msg <- queue.NewMessage(msg)
-		}
-	}()
+	srv := api.NewServer(cfg.HTTPAddr, recordRepo, historyRepo, changeRepo, taskRepo, jobRepo, jobManager, scheduleRepo, pr, h, broker)
+	srv.Start()
+
+	sched := scheduler.NewScheduler(scheduleRepo, jobManager, h, cfg.SchedulerInterval)
+	if cfg.SchedulerEnabled {
+		sched.Start()
+	}
+
+	go wp.Consume(msgs)
 	<-sigs
-	ch.Close()
-	wp.Stop()
+
+	if cfg.SchedulerEnabled {
+		sched.Stop()
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		utils.Logger.Printf("Ошибка остановки HTTP сервера: %v", err)
+	}
+
+	// Сначала перестаём брать сообщения и дожидаемся задач в работе: их ack идёт через брокер,
+	// а результаты - в MongoDB, поэтому брокер закрывается после пула, а MongoDB - последней
+	broker.StopConsuming()
+	drainCtx, cancelDrain := context.WithTimeout(ctx, cfg.ShutdownTimeout)
+	defer cancelDrain()
+	wp.Shutdown(drainCtx)
+
+	if err := broker.Close(); err != nil {
+		utils.Logger.Printf("Ошибка закрытия очереди: %v", err)
+	} else {
+		utils.Logger.Println("Очередь успешно закрыта.")
+	}
+}
+
+// embeddedIDMigrator - репозиторий, умеющий переносить документы со старой схемой ID
+type embeddedIDMigrator interface {
+	MigrateEmbeddedIDs(ctx context.Context) (int64, error)
+}
+
+func migrateEmbeddedIDs(ctx context.Context, coll string, repo embeddedIDMigrator) {
+	n, err := repo.MigrateEmbeddedIDs(ctx)
+	if err != nil {
+		utils.Logger.Fatalf("Ошибка миграции ID коллекции %s: %v", coll, err)
+	}
+	if n > 0 {
+		utils.Logger.Printf("Коллекция %s: перенесено документов со старой схемой ID: %d", coll, n)
+	}
+}
+
+// syncIndexes приводит индексы коллекции к объявленным (ensure) или только сообщает
+// о расхождениях (report). Запуск останавливает только ошибка EnsureIndexes. Уникальный
+// индекс, который не удалось создать из-за дубликатов в данных, попадает в
+// IndexDrift.Duplicates и только логируется: до очистки данных уникальность не соблюдается.
+func syncIndexes[T database.Entity](ctx context.Context, mode, coll string, repo database.Repository[T], indexes []database.Index) {
+	var (
+		drift *database.IndexDrift
+		err   error
+	)
+	switch mode {
+	case database.IndexModeOff:
+		return
+	case database.IndexModeReport:
+		drift, err = repo.CheckIndexes(ctx, indexes)
+		if err != nil {
+			utils.Logger.Printf("Ошибка проверки индексов %s: %v", coll, err)
+			return
+		}
+	default:
+		drift, err = repo.EnsureIndexes(ctx, indexes)
+		if err != nil {
+			utils.Logger.Fatalf("Ошибка создания индексов %s: %v", coll, err)
+		}
+	}
+
+	if len(drift.Created) > 0 {
+		utils.Logger.Printf("Индексы %s созданы: %s", coll, strings.Join(drift.Created, ", "))
+	}
+	if len(drift.Missing) > 0 {
+		utils.Logger.Printf("[INDEX] %s: нет индексов %s", coll, strings.Join(drift.Missing, ", "))
+	}
+	if len(drift.Duplicates) > 0 {
+		utils.Logger.Printf("[INDEX] %s: уникальные индексы %s не созданы из-за дубликатов в данных, удалите их (см. README, раздел \"Индексы\")",
+			coll, strings.Join(drift.Duplicates, ", "))
+	}
+	if len(drift.Changed) > 0 {
+		utils.Logger.Printf("[INDEX] %s: индексы отличаются от объявленных, пересоздайте вручную: %s", coll, strings.Join(drift.Changed, ", "))
+	}
+	if len(drift.Extra) > 0 {
+		utils.Logger.Printf("[INDEX] %s: необъявленные индексы %s", coll, strings.Join(drift.Extra, ", "))
+	}
 }